@@ -0,0 +1,202 @@
+package httpx
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrAsyncWriterClosed is returned when writing to a closed AsyncWriter.
+var ErrAsyncWriterClosed = errors.New("async writer closed")
+
+// AsyncWriterConfig is the configuration for an AsyncWriter.
+type AsyncWriterConfig struct {
+	// BufferSize is the maximum number of pending writes (default: 1024).
+	BufferSize int
+
+	// BatchSize is the maximum number of pending writes that are
+	// written to the underlying writer at once (default: 64).
+	BatchSize int
+
+	// Block makes Write wait for space when the buffer is full. By
+	// default the write is dropped and counted, see Dropped.
+	Block bool
+}
+
+var DefaultAsyncWriterConfig = AsyncWriterConfig{
+	BufferSize: 1024,
+	BatchSize:  64,
+}
+
+// AsyncWriter is an io.Writer that queues writes in a bounded ring buffer
+// and writes them to the underlying writer from a background goroutine, so
+// a slow disk or pipe doesn't add latency to the requests. It is meant to
+// be used as LoggerConfig.Output, where every Write is a log entry.
+//
+// Usage:
+//
+//	out := httpx.NewAsyncWriter(os.Stdout, httpx.DefaultAsyncWriterConfig)
+//	defer out.Close()
+//
+//	mux.Use(httpx.LoggerWithConfig(httpx.LoggerConfig{
+//		Format: httpx.DefaultLoggerConfig.Format,
+//		Output: out,
+//	}))
+type AsyncWriter struct {
+	w   io.Writer
+	cfg AsyncWriterConfig
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	ring    [][]byte
+	head    int
+	count   int
+	writing bool
+	closed  bool
+	err     error
+
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+var _ io.WriteCloser = &AsyncWriter{}
+
+// NewAsyncWriter returns an AsyncWriter that writes to w and starts its
+// background goroutine. Call Close to stop it.
+func NewAsyncWriter(w io.Writer, cfg AsyncWriterConfig) *AsyncWriter {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = DefaultAsyncWriterConfig.BufferSize
+	}
+
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultAsyncWriterConfig.BatchSize
+	}
+
+	a := &AsyncWriter{
+		w:    w,
+		cfg:  cfg,
+		ring: make([][]byte, cfg.BufferSize),
+		done: make(chan struct{}),
+	}
+	a.cond = sync.NewCond(&a.mu)
+
+	go a.run()
+	return a
+}
+
+// Write queues a copy of p. When the buffer is full, the write is dropped
+// unless Block is set. It never returns the errors of the underlying writer,
+// those are returned by Flush and Close.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for a.count == len(a.ring) && a.cfg.Block && !a.closed {
+		a.cond.Wait()
+	}
+
+	if a.closed {
+		return 0, ErrAsyncWriterClosed
+	}
+
+	if a.count == len(a.ring) {
+		a.dropped.Add(1)
+		return len(p), nil
+	}
+
+	a.ring[(a.head+a.count)%len(a.ring)] = append([]byte(nil), p...)
+	a.count++
+	a.cond.Broadcast()
+	return len(p), nil
+}
+
+// Dropped returns the number of writes dropped because the buffer was full.
+func (a *AsyncWriter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Flush waits until all the queued writes have been written and returns the
+// last error of the underlying writer, if any.
+func (a *AsyncWriter) Flush() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for (a.count > 0 || a.writing) && !a.isStopped() {
+		a.cond.Wait()
+	}
+
+	err := a.err
+	a.err = nil
+	return err
+}
+
+// Close flushes the queued writes and stops the background goroutine. It
+// doesn't close the underlying writer.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.cond.Broadcast()
+	a.mu.Unlock()
+
+	<-a.done
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// isStopped reports whether the background goroutine has finished.
+func (a *AsyncWriter) isStopped() bool {
+	select {
+	case <-a.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// run writes the queued entries in batches until the writer is closed and
+// the buffer drained.
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+	var batch []byte
+
+	for {
+		a.mu.Lock()
+		for a.count == 0 && !a.closed {
+			a.cond.Wait()
+		}
+
+		if a.count == 0 && a.closed {
+			a.cond.Broadcast()
+			a.mu.Unlock()
+			return
+		}
+
+		batch = batch[:0]
+		for n := 0; n < a.cfg.BatchSize && a.count > 0; n++ {
+			batch = append(batch, a.ring[a.head]...)
+			a.ring[a.head] = nil
+			a.head = (a.head + 1) % len(a.ring)
+			a.count--
+		}
+		a.writing = true
+		a.cond.Broadcast()
+		a.mu.Unlock()
+
+		_, err := a.w.Write(batch)
+
+		a.mu.Lock()
+		a.writing = false
+		if err != nil {
+			a.err = err
+		}
+		a.cond.Broadcast()
+		a.mu.Unlock()
+	}
+}
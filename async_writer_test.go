@@ -0,0 +1,77 @@
+package httpx_test
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/bluescreen10/httpx"
+)
+
+// blockingWriter blocks every write until release is closed.
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func TestAsyncWriter(t *testing.T) {
+	out := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	close(out.release)
+
+	w := httpx.NewAsyncWriter(out, httpx.DefaultAsyncWriterConfig)
+	for _, line := range []string{"a\n", "b\n", "c\n"} {
+		w.Write([]byte(line))
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := out.buf.String(); got != "a\nb\nc\n" {
+		t.Fatalf("expected 'a\\nb\\nc\\n' got '%s'", got)
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte("d\n")); !errors.Is(err, httpx.ErrAsyncWriterClosed) {
+		t.Fatalf("expected ErrAsyncWriterClosed got '%v'", err)
+	}
+}
+
+func TestAsyncWriterDrop(t *testing.T) {
+	out := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	w := httpx.NewAsyncWriter(out, httpx.AsyncWriterConfig{BufferSize: 2, BatchSize: 1})
+
+	// the first write is taken by the background goroutine, which
+	// blocks until released
+	w.Write([]byte("1"))
+	<-out.started
+
+	for _, entry := range []string{"2", "3", "4", "5"} {
+		w.Write([]byte(entry))
+	}
+
+	if dropped := w.Dropped(); dropped != 2 {
+		t.Fatalf("expected '2' dropped writes got '%d'", dropped)
+	}
+
+	close(out.release)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := out.buf.String(); got != "123" {
+		t.Fatalf("expected '123' got '%s'", got)
+	}
+}
@@ -0,0 +1,194 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPConfig is the configuration for a ClientIPResolver.
+type ClientIPConfig struct {
+	// TrustedProxies are the addresses (e.g. "10.0.0.1") or CIDRs (e.g.
+	// "10.0.0.0/8") of the proxies trusted to set the forwarding header.
+	// When empty, the forwarding header is ignored.
+	TrustedProxies []string
+
+	// Header is the forwarding header written by the trusted proxies:
+	// "Forwarded", "X-Forwarded-For" or "X-Real-IP". It is required when
+	// TrustedProxies is set. Only this header is used, as the client can
+	// send any of the others and the proxy passes them through unchanged.
+	Header string
+}
+
+var DefaultClientIPConfig = ClientIPConfig{
+	Header: "X-Forwarded-For",
+}
+
+// ClientIPResolver resolves the IP of the client that made a request, taking
+// into account the forwarding header set by trusted proxies. The header is
+// walked from the closest hop backwards, and the first address that is not
+// a trusted proxy is the client.
+//
+// Usage:
+//
+//	resolver, err := httpx.NewClientIPResolver(httpx.ClientIPConfig{
+//		TrustedProxies: []string{"10.0.0.0/8"},
+//		Header:         "X-Forwarded-For",
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	// must run before the middlewares that use the IP, like Logger
+//	mux.Use(resolver.Handler, httpx.Logger())
+//
+//	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+//		fmt.Fprintf(w, "your ip is %s", httpx.ClientIP(r))
+//	})
+type ClientIPResolver struct {
+	trusted []netip.Prefix
+	header  string
+}
+
+// NewClientIPResolver returns a ClientIPResolver with the given configuration.
+// It returns an error if any of the trusted proxies is not a valid address
+// or CIDR, or if they are set without a header.
+func NewClientIPResolver(cfg ClientIPConfig) (*ClientIPResolver, error) {
+	if len(cfg.TrustedProxies) > 0 && cfg.Header == "" {
+		return nil, errors.New("client ip: header is required with trusted proxies")
+	}
+
+	res := &ClientIPResolver{header: http.CanonicalHeaderKey(cfg.Header)}
+	for _, proxy := range cfg.TrustedProxies {
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			res.trusted = append(res.trusted, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+		addr = addr.Unmap()
+		res.trusted = append(res.trusted, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return res, nil
+}
+
+// Handler is a middleware that resolves the client IP and stores it in the
+// request context, where it can be retrieved with ClientIP.
+func (res *ClientIPResolver) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := res.Resolve(r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey, ip)))
+	})
+}
+
+// Resolve returns the IP of the client that made the request.
+func (res *ClientIPResolver) Resolve(r *http.Request) string {
+	remote := remoteIP(r)
+
+	addr, err := netip.ParseAddr(remote)
+	if err != nil || !res.isTrusted(addr) {
+		return remote
+	}
+
+	values := r.Header.Values(res.header)
+	if len(values) == 0 {
+		return addr.String()
+	}
+
+	var hops []string
+	switch res.header {
+	case "Forwarded":
+		hops = parseForwarded(values)
+	case "X-Real-Ip":
+		hops = []string{strings.TrimSpace(values[len(values)-1])}
+	default:
+		for _, value := range values {
+			for _, hop := range strings.Split(value, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+	}
+
+	return res.walk(hops, addr.String())
+}
+
+// walk returns the first hop, from the right, that is not a trusted proxy.
+// If a hop is not a valid IP, the last valid one is returned, as nothing
+// before it can be trusted.
+func (res *ClientIPResolver) walk(hops []string, current string) string {
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := parseHopAddr(hops[i])
+		if err != nil {
+			return current
+		}
+
+		current = addr.String()
+		if !res.isTrusted(addr) {
+			return current
+		}
+	}
+	return current
+}
+
+func (res *ClientIPResolver) isTrusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range res.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client IP stored in the request context by
+// ClientIPResolver.Handler, or the host of r.RemoteAddr if there is none.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// remoteIP returns the host of r.RemoteAddr.
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// parseForwarded returns the "for" parameters of the RFC 7239 Forwarded
+// header values, in order.
+func parseForwarded(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					hops = append(hops, strings.Trim(val, `"`))
+				}
+			}
+		}
+	}
+	return hops
+}
+
+// parseHopAddr parses an address of a forwarding header, which can include
+// a port and brackets for IPv6 addresses ("[2001:db8::1]:8080").
+func parseHopAddr(hop string) (netip.Addr, error) {
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap(), nil
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]"))
+	return addr.Unmap(), err
+}
@@ -0,0 +1,107 @@
+package httpx_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bluescreen10/httpx"
+)
+
+func TestClientIPResolver(t *testing.T) {
+	tests := []struct {
+		remote   string
+		header   string
+		value    string
+		expected string
+	}{
+		{"203.0.113.5:1234", "X-Forwarded-For", "1.2.3.4", "203.0.113.5"},
+		{"10.0.0.1:1234", "", "", "10.0.0.1"},
+		{"10.0.0.1:1234", "X-Forwarded-For", "1.2.3.4", "1.2.3.4"},
+		{"10.0.0.1:1234", "X-Forwarded-For", "6.6.6.6, 1.2.3.4, 10.0.0.2", "1.2.3.4"},
+		{"10.0.0.1:1234", "X-Forwarded-For", "garbage, 10.0.0.2", "10.0.0.2"},
+		{"192.168.1.1:1234", "X-Real-IP", "1.2.3.4", "1.2.3.4"},
+		{"10.0.0.1:1234", "Forwarded", `for=1.2.3.4;proto=https, for="[2001:db8::1]:8080"`, "1.2.3.4"},
+		{"10.0.0.1:1234", "Forwarded", `for="[2001:db9::1]:8080"`, "2001:db9::1"},
+	}
+
+	for _, tt := range tests {
+		header := tt.header
+		if header == "" {
+			header = "X-Forwarded-For"
+		}
+
+		res, err := httpx.NewClientIPResolver(httpx.ClientIPConfig{
+			TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"},
+			Header:         header,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := httptest.NewRequest("GET", "/", &bytes.Buffer{})
+		r.RemoteAddr = tt.remote
+		if tt.header != "" {
+			r.Header.Set(tt.header, tt.value)
+		}
+
+		if got := res.Resolve(r); got != tt.expected {
+			t.Fatalf("%s: %s expected ip '%s' got '%s'", tt.header, tt.value, tt.expected, got)
+		}
+	}
+
+	if _, err := httpx.NewClientIPResolver(httpx.ClientIPConfig{TrustedProxies: []string{"nope"}, Header: "X-Real-IP"}); err == nil {
+		t.Fatal("expected error for invalid trusted proxy")
+	}
+
+	if _, err := httpx.NewClientIPResolver(httpx.ClientIPConfig{TrustedProxies: []string{"10.0.0.0/8"}}); err == nil {
+		t.Fatal("expected error for trusted proxies without header")
+	}
+}
+
+func TestClientIPSpoofedHeader(t *testing.T) {
+	// the proxy only appends X-Forwarded-For, the client sends the others
+	res, err := httpx.NewClientIPResolver(httpx.ClientIPConfig{
+		TrustedProxies: []string{"10.0.0.0/8"},
+		Header:         "X-Forwarded-For",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/", &bytes.Buffer{})
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("Forwarded", "for=1.1.1.1")
+	r.Header.Set("X-Real-IP", "2.2.2.2")
+	r.Header.Set("X-Forwarded-For", "203.0.113.9")
+
+	if got := res.Resolve(r); got != "203.0.113.9" {
+		t.Fatalf("expected ip '203.0.113.9' got '%s'", got)
+	}
+}
+
+func TestClientIPLogger(t *testing.T) {
+	res, _ := httpx.NewClientIPResolver(httpx.ClientIPConfig{
+		TrustedProxies: []string{"10.0.0.0/8"},
+		Header:         "X-Forwarded-For",
+	})
+
+	output := &bytes.Buffer{}
+	logger := httpx.LoggerWithConfig(httpx.LoggerConfig{Format: "${ip}", Output: output})
+
+	var handlerIP string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerIP = httpx.ClientIP(r)
+	})
+
+	r := httptest.NewRequest("GET", "/", &bytes.Buffer{})
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	w := httptest.NewRecorder()
+	res.Handler(logger(h)).ServeHTTP(w, r)
+
+	if got := output.String(); got != "1.2.3.4" || handlerIP != "1.2.3.4" {
+		t.Fatalf("expected ip '1.2.3.4' got '%s' and '%s'", got, handlerIP)
+	}
+}
@@ -1,5 +1,5 @@
-// Codec defines how session values and metadata (like creation time)
-// are serialized to and from bytes, allowing them to be stored or transmitted.
+// Codec defines how session values and metadata (like creation and last
+// activity time) are serialized to and from bytes, allowing them to be stored or transmitted.
 // The package includes a default implementation using Go's `encoding/gob`.
 package httpx
 
@@ -18,26 +18,71 @@ type Codec interface {
 	Encode(createdAt time.Time, values map[string]any) (data []byte, err error)
 }
 
-// Ensure gobCodec implements Codec.
-var _ Codec = GobCodec{}
+// CodecWithActivity is a Codec that also serializes the last activity time
+// of the session, used to enforce the idle timeout on the server. For codecs
+// that don't implement it, the idle timeout relies on the expiration of the
+// store, which is extended on every request.
+type CodecWithActivity interface {
+	Codec
+
+	// DecodeWithActivity decodes byte slice into the session creation
+	// time, last activity time and values.
+	DecodeWithActivity(data []byte) (createdAt, lastActivity time.Time, values map[string]any, err error)
+
+	// EncodeWithActivity encodes the creation time, last activity time and
+	// session values into a byte slice.
+	EncodeWithActivity(createdAt, lastActivity time.Time, values map[string]any) (data []byte, err error)
+}
+
+// Ensure gobCodec implements CodecWithActivity.
+var _ CodecWithActivity = GobCodec{}
 
 // gobCodec is a Codec implementation using Go's encoding/gob. It serializes
-// a gobData struct containing the creation time and session values.
+// a gobData struct containing the creation time, last activity time and
+// session values.
+//
+// The values are stored as interfaces, so gob needs their types registered.
+// Basic types, time.Time, []string, []int and map[string]any are registered
+// by the package; other types, like structs, must be registered by the
+// application before being stored in a session:
+//
+//	gob.Register(User{})
 type GobCodec struct{}
 
+func init() {
+	gob.Register(time.Time{})
+	gob.Register([]string{})
+	gob.Register([]int{})
+	gob.Register(map[string]any{})
+}
+
 type gobData struct {
-	CreatedAt time.Time
-	Values    map[string]any
+	CreatedAt    time.Time
+	LastActivity time.Time
+	Values       map[string]any
 }
 
 // Encode serializes the creation time and session values into a byte slice
 // using gob encoding.
-func (GobCodec) Encode(createdAt time.Time, values map[string]any) ([]byte, error) {
+func (c GobCodec) Encode(createdAt time.Time, values map[string]any) ([]byte, error) {
+	return c.EncodeWithActivity(createdAt, time.Time{}, values)
+}
+
+// Decode deserializes the data into a creation time and session values
+// using gob decoding.
+func (c GobCodec) Decode(data []byte) (time.Time, map[string]any, error) {
+	createdAt, _, values, err := c.DecodeWithActivity(data)
+	return createdAt, values, err
+}
+
+// EncodeWithActivity serializes the creation time, last activity time and
+// session values into a byte slice using gob encoding.
+func (GobCodec) EncodeWithActivity(createdAt, lastActivity time.Time, values map[string]any) ([]byte, error) {
 
 	var buf bytes.Buffer
 	encoder := gob.NewEncoder(&buf)
 
-	err := encoder.Encode(&gobData{CreatedAt: createdAt, Values: values})
+	err := encoder.Encode(&gobData{CreatedAt: createdAt, LastActivity: lastActivity, Values: values})
 	if err != nil {
 		return nil, err
 	}
@@ -45,14 +90,15 @@ func (GobCodec) Encode(createdAt time.Time, values map[string]any) ([]byte, erro
 	return buf.Bytes(), nil
 }
 
-// Decode deserializes the data into a creation time and session values
-// using gob decoding.
-func (GobCodec) Decode(data []byte) (time.Time, map[string]any, error) {
+// DecodeWithActivity deserializes the data into a creation time, last
+// activity time and session values using gob decoding. The last activity
+// time is zero for data encoded without it.
+func (GobCodec) DecodeWithActivity(data []byte) (time.Time, time.Time, map[string]any, error) {
 
 	buf := bytes.NewBuffer(data)
 	decoder := gob.NewDecoder(buf)
 
 	var d gobData
 	err := decoder.Decode(&d)
-	return d.CreatedAt, d.Values, err
+	return d.CreatedAt, d.LastActivity, d.Values, err
 }
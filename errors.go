@@ -0,0 +1,165 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// HTTPError is an error that carries the HTTP status code that should be
+// sent to the client. Handlers can return it to control the response
+// written by the error handler.
+//
+// Usage:
+//
+//	mux.Handle("GET /users/{id}", httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+//		user, err := findUser(r.PathValue("id"))
+//		if err != nil {
+//			return httpx.NewHTTPError(http.StatusNotFound, "user not found", err)
+//		}
+//		return json.NewEncoder(w).Encode(user)
+//	}))
+type HTTPError struct {
+	// Status is the HTTP status code of the response.
+	Status int
+
+	// Message is the message sent to the client. If empty, the status
+	// text is used.
+	Message string
+
+	// Err is the underlying error, it is not sent to the client.
+	Err error
+}
+
+// NewHTTPError returns an HTTPError with the given status, message and
+// optional underlying error.
+func NewHTTPError(status int, message string, err ...error) *HTTPError {
+	return &HTTPError{Status: status, Message: message, Err: errors.Join(err...)}
+}
+
+// Error returns the message of the error, followed by the underlying error
+// if there is one.
+func (e *HTTPError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+func (e *HTTPError) statusCode() int {
+	return e.Status
+}
+
+// statusError is implemented by the errors of this package that carry
+// the status code of the response.
+type statusError interface {
+	error
+	statusCode() int
+}
+
+// HandlerFunc is an adapter that allows using functions that return an error
+// as HTTP handlers. When the function returns an error, it is recorded on the
+// request (so the Logger can print it in ${error}) and passed to the error
+// handler of the ServeMux serving the request, or DefaultErrorHandler if none
+// is set.
+type HandlerFunc func(http.ResponseWriter, *http.Request) error
+
+// ServeHTTP calls f(w, r) and handles the returned error.
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f(w, r); err != nil {
+		WriteError(w, r, err)
+	}
+}
+
+// ErrorHandler writes the response for an error returned by a handler.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// DefaultErrorHandler writes the error as plain text. The status code is taken
+// from HTTPError, Problem or BodyError, other errors result in a 500 Internal
+// Server Error without exposing the error message to the client.
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	status := ErrorStatus(err)
+	http.Error(w, errorMessage(err, status), status)
+}
+
+// ErrorStatus returns the HTTP status code for the error. It is the status
+// of the HTTPError, Problem or BodyError in the chain, or 500 Internal Server
+// Error otherwise.
+func ErrorStatus(err error) int {
+	var statusErr statusError
+	if errors.As(err, &statusErr) && statusErr.statusCode() != 0 {
+		return statusErr.statusCode()
+	}
+	return http.StatusInternalServerError
+}
+
+// errorMessage returns the message of the error that is safe to send to
+// the client.
+func errorMessage(err error, status int) string {
+	var httpErr *HTTPError
+	var problem *Problem
+	var bodyErr *BodyError
+
+	switch {
+	case errors.As(err, &httpErr) && httpErr.Message != "":
+		return httpErr.Message
+	case errors.As(err, &problem) && problem.Detail != "":
+		return problem.Detail
+	case errors.As(err, &bodyErr):
+		return bodyErr.Error()
+	}
+	return http.StatusText(status)
+}
+
+// WriteError records the error on the request and writes the response using
+// the error handler of the ServeMux serving the request. It allows regular
+// http.Handlers to report errors the same way HandlerFunc does.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if slot, ok := r.Context().Value(errorSlotKey).(*errorSlot); ok {
+		slot.err = err
+	}
+
+	handler, ok := r.Context().Value(errorHandlerKey).(ErrorHandler)
+	if !ok || handler == nil {
+		handler = DefaultErrorHandler
+	}
+	handler(w, r, err)
+}
+
+// RequestError returns the error recorded on the request by HandlerFunc or
+// WriteError. It only works for requests that passed through a middleware
+// that tracks errors, like Logger.
+func RequestError(r *http.Request) error {
+	if slot, ok := r.Context().Value(errorSlotKey).(*errorSlot); ok {
+		return slot.err
+	}
+	return nil
+}
+
+// errorSlot holds the error returned while handling a request. It is
+// stored in the request context by middlewares that want to inspect the
+// error after the handler returns.
+type errorSlot struct {
+	err error
+}
+
+// withErrorSlot returns a request that can record handler errors, and
+// the slot where they are recorded.
+func withErrorSlot(r *http.Request) (*http.Request, *errorSlot) {
+	if slot, ok := r.Context().Value(errorSlotKey).(*errorSlot); ok {
+		return r, slot
+	}
+
+	slot := &errorSlot{}
+	return r.WithContext(context.WithValue(r.Context(), errorSlotKey, slot)), slot
+}
@@ -0,0 +1,89 @@
+package httpx_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bluescreen10/httpx"
+)
+
+func TestHandlerFuncError(t *testing.T) {
+	h := httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return httpx.NewHTTPError(http.StatusNotFound, "user not found", errors.New("no rows"))
+	})
+
+	r := httptest.NewRequest("GET", "/", &bytes.Buffer{})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status '404' got '%d'", w.Code)
+	}
+
+	if body := w.Body.String(); body != "user not found\n" {
+		t.Fatalf("expected body 'user not found' got '%s'", body)
+	}
+}
+
+func TestHandlerFuncInternalError(t *testing.T) {
+	h := httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("secret")
+	})
+
+	r := httptest.NewRequest("GET", "/", &bytes.Buffer{})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status '500' got '%d'", w.Code)
+	}
+
+	if body := w.Body.String(); body != "Internal Server Error\n" {
+		t.Fatalf("expected body 'Internal Server Error' got '%s'", body)
+	}
+}
+
+func TestMuxErrorHandler(t *testing.T) {
+	mux := httpx.NewServeMux()
+	mux.SetErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(httpx.ErrorStatus(err))
+		w.Write([]byte("site: " + err.Error()))
+	})
+
+	api := mux.Group("/api")
+	api.SetErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(httpx.ErrorStatus(err))
+		w.Write([]byte("api: " + err.Error()))
+	})
+
+	failing := httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return httpx.NewHTTPError(http.StatusBadRequest, "bad")
+	})
+	mux.Handle("/page", failing)
+	api.Handle("/resource", failing)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/page", "site: bad"},
+		{"/api/resource", "api: bad"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.path, &bytes.Buffer{})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected status '400' got '%d'", w.Code)
+		}
+
+		if body := w.Body.String(); body != tt.expected {
+			t.Fatalf("expected body '%s' got '%s'", tt.expected, body)
+		}
+	}
+}
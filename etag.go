@@ -1,5 +1,5 @@
 // ETag provides an HTTP middleware that calculates and sets
-// ETag headers for GET requests. It can optionally use a cache to
+// ETag headers for GET and HEAD requests. It can optionally use a cache to
 // avoid recalculating ETags and supports weak ETags.
 //
 // This middleware allows clients to make conditional requests using
@@ -19,16 +19,68 @@
 //
 //	http.ListenAndServe(":8080", etag(handler))
 //
-// Only GET requests are supported. Responses for other HTTP methods
-// are passed through unmodified.
+// ETags are computed for GET and HEAD requests. The preconditions of RFC 9110
+// (If-Match, If-None-Match, If-Modified-Since and If-Unmodified-Since) are
+// evaluated against the computed ETag and the Last-Modified header set by
+// the handler, responding with 304 Not Modified or 412 Precondition Failed.
+//
+// For other methods, like PUT or PATCH, the preconditions are evaluated
+// before calling the handler against the current validators of the
+// resource, taken from Validators or the cache (see UseCache). This gives
+// optimistic concurrency control: a client sending If-Match with an
+// outdated ETag gets 412 Precondition Failed and the handler isn't called.
+// When the validators are unknown the request is passed through.
+//
+// When Validators returns an ETag, it is sent as is on GET and HEAD
+// responses and the body is streamed without being hashed, so clients
+// get back the same ETag they must send in If-Match.
+//
+// HEAD responses get the same ETag as GET, computed from the body written
+// by the handler (which isn't sent). If the handler doesn't write a body
+// for HEAD requests, the ETag is taken from Validators or the cache.
+//
+// ETags are computed with CRC64 by default. Hasher allows choosing a
+// different hash function, trading speed for collision resistance:
+//
+//	etag := httpx.ETagWithConfig(httpx.ETagConfig{
+//		Hasher: httpx.ETagSHA256,
+//	})
+//
+// Any function returning a hash.Hash can be used, e.g. from xxhash:
+//
+//	etag := httpx.ETagWithConfig(httpx.ETagConfig{
+//		Hasher: func() hash.Hash { return xxhash.New() },
+//	})
+//
+// To compute the ETag the response body is buffered. Responses that can't
+// or shouldn't be buffered are passed through instead:
+//   - responses with a status other than 200 OK
+//   - Server-Sent Events ("Content-Type: text/event-stream")
+//   - responses whose handler already set an ETag header, in which case
+//     If-None-Match is checked against it
+//   - responses larger than MaxBufferSize
+//   - responses flushed by the handler
+//   - connections hijacked by the handler, e.g. for WebSockets
+//
+// Responses can also be streamed while the ETag is computed, which is then
+// sent as a trailer. This is enabled for all responses with UseTrailer, or
+// per response when the handler declares it with w.Header().Set("Trailer",
+// "ETag") before writing the body.
 package httpx
 
 import (
+	"bufio"
 	"bytes"
-	"fmt"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
 	"hash/crc64"
+	"hash/fnv"
+	"io"
+	"net"
 	"net/http"
-	"sync"
+	"strings"
+	"time"
 )
 
 // ETag Configuration
@@ -37,76 +89,471 @@ type ETagConfig struct {
 	// prevents recomputing the ETag for every request.
 	UseCache bool
 
+	// Cache is the cache used to store the ETags. It implies UseCache.
+	// When nil and UseCache is set, a cache with DefaultETagCacheConfig
+	// is created. Setting it allows invalidating ETags when the content
+	// changes, see ETagCache.
+	Cache *ETagCache
+
 	// Uses the prefix "W/" in the ETag header
 	IsWeak bool
+
+	// MaxBufferSize is the maximum size of a response body that is
+	// buffered to compute the ETag. Larger responses are passed through
+	// without an ETag. Zero means no limit.
+	MaxBufferSize int
+
+	// UseTrailer streams all the responses while computing the ETag, and
+	// sends it as a trailer instead of a header. Clients only get 304
+	// responses for cached ETags (see UseCache).
+	UseTrailer bool
+
+	// Validators, if set, returns the current ETag and last modification
+	// time of the resource targeted by the request, so preconditions can
+	// be evaluated before calling the handler. Empty values mean unknown.
+	// The returned ETag is used for GET and HEAD responses instead of
+	// computing one from the body.
+	Validators func(r *http.Request) (etag string, lastModified time.Time)
+
+	// Hasher returns the hash used to compute the ETag from the response
+	// body (default: ETagCRC64).
+	Hasher func() hash.Hash
 }
 
-var DefaultETagConfig = ETagConfig{}
+var DefaultETagConfig = ETagConfig{
+	Hasher: ETagCRC64,
+}
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// Hash functions for ETagConfig.Hasher.
+var (
+	// ETagCRC64 uses CRC-64 (ECMA), which is fast but not collision
+	// resistant.
+	ETagCRC64 = func() hash.Hash { return crc64.New(crc64Table) }
+
+	// ETagFNV uses 64-bit FNV-1a.
+	ETagFNV = func() hash.Hash { return fnv.New64a() }
+
+	// ETagSHA256 uses SHA-256, which is slower but collision resistant.
+	ETagSHA256 = func() hash.Hash { return sha256.New() }
+)
 
 // ETag returs a middleware with the default configuration that set and checks
-// ETags headers. For GET requests, it calculates an ETag based on the response
-// body and sets the ETag header. If the client sends If-None-Match matching
-// the ETag, a 304 Not Modified is returned.
+// ETags headers. For GET and HEAD requests, it calculates an ETag based on the
+// response body and sets the ETag header. If the client sends If-None-Match
+// matching the ETag, a 304 Not Modified is returned.
 func ETag() Middleware {
 	return ETagWithConfig(DefaultETagConfig)
 }
 
 // ETagWithConfig returs am ETag middleware with the specified configuration.
 func ETagWithConfig(cfg ETagConfig) Middleware {
+	if cfg.Hasher == nil {
+		cfg.Hasher = DefaultETagConfig.Hasher
+	}
+
 	return func(next http.Handler) http.Handler {
-		var cache sync.Map
+		cache := cfg.Cache
+		if cache == nil && cfg.UseCache {
+			cache = NewETagCache(DefaultETagCacheConfig)
+		}
 
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// For now only GET supported
-			if r.Method != http.MethodGet {
-				next.ServeHTTP(w, r)
-				return
+			var etag, validator string
+			var lastModified time.Time
+			if cfg.Validators != nil {
+				etag, lastModified = cfg.Validators(r)
+				validator = etag
 			}
 
-			uri := r.URL.RequestURI()
-			cachedEtag, ok := cache.Load(uri)
-			clientEtag := r.Header.Get("If-None-Match")
+			if cache != nil && etag == "" {
+				etag = cache.get(r)
+			}
+
+			if etag != "" || !lastModified.IsZero() {
+				if status := evalPreconditions(r, etag, lastModified); status != 0 {
+					writePreconditionStatus(w, status, etag)
+					return
+				}
+			}
+
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				rw := NewResponseWriter(w)
+				next.ServeHTTP(rw, r)
 
-			if cfg.UseCache && ok && clientEtag == cachedEtag {
-				w.WriteHeader(http.StatusNotModified)
+				// the resource may have changed
+				status := rw.Status()
+				if cache != nil && status >= 200 && status < 300 && !isSafeMethod(r.Method) {
+					cache.Invalidate(requestURI(r))
+				}
 				return
 			}
 
-			buf := &bytes.Buffer{}
-			table := crc64.MakeTable(crc64.ECMA)
-			header := w.Header()
-			rw := newResponseWriter(buf, header, nil)
-			next.ServeHTTP(rw, r)
+			ew := &etagWriter{
+				w:         w,
+				r:         r,
+				cfg:       &cfg,
+				etag:      etag,
+				validator: validator,
+				hash:      cfg.Hasher(),
+			}
+			next.ServeHTTP(ew, r)
+
+			etag = ew.finish()
+			if cache != nil && etag != "" {
+				cache.set(r, w.Header(), etag)
+			}
+		})
+	}
+}
+
+// etag writer modes, decided when the handler writes the header or the
+// first chunk of the body.
+const (
+	etagUndecided = iota
+	etagBuffering
+	etagStreaming
+	etagPassthrough
+	etagDiscard
+)
+
+// etagWriter is the http.ResponseWriter used by the ETag middleware. It
+// buffers the body to compute the ETag, or streams it when buffering isn't
+// possible or wanted.
+type etagWriter struct {
+	w   http.ResponseWriter
+	r   *http.Request
+	cfg *ETagConfig
+
+	// etag is the known ETag of the resource, used for HEAD responses
+	// without a body.
+	etag string
+
+	// validator is the ETag returned by Validators, sent instead of
+	// computing one. The preconditions were already evaluated against it.
+	validator string
+
+	mode   int
+	status int
+	buf    bytes.Buffer
+	hash   hash.Hash
+}
+
+var (
+	_ http.ResponseWriter = &etagWriter{}
+	_ http.Flusher        = &etagWriter{}
+	_ http.Hijacker       = &etagWriter{}
+	_ http.Pusher         = &etagWriter{}
+	_ io.ReaderFrom       = &etagWriter{}
+)
+
+func (ew *etagWriter) Header() http.Header {
+	return ew.w.Header()
+}
+
+func (ew *etagWriter) WriteHeader(status int) {
+	if ew.mode != etagUndecided || status < 200 {
+		if status < 200 {
+			ew.w.WriteHeader(status)
+		}
+		return
+	}
+
+	ew.status = status
+	ew.decide()
+}
+
+func (ew *etagWriter) Write(data []byte) (int, error) {
+	if ew.mode == etagUndecided {
+		ew.status = http.StatusOK
+		ew.decide()
+	}
+
+	switch ew.mode {
+	case etagDiscard:
+		return len(data), nil
+
+	case etagStreaming:
+		ew.hash.Write(data)
+		return ew.w.Write(data)
+
+	case etagBuffering:
+		if ew.cfg.MaxBufferSize > 0 && ew.buf.Len()+len(data) > ew.cfg.MaxBufferSize {
+			ew.passthrough()
+			return ew.w.Write(data)
+		}
+		return ew.buf.Write(data)
+	}
+
+	return ew.w.Write(data)
+}
+
+// Flush stops buffering the response, since the handler wants the data
+// sent as soon as possible, and flushes the underlying writer.
+func (ew *etagWriter) Flush() {
+	if ew.mode == etagUndecided {
+		ew.status = http.StatusOK
+		ew.decide()
+	}
+
+	if ew.mode == etagBuffering {
+		ew.passthrough()
+	}
+
+	if ew.mode != etagDiscard {
+		http.NewResponseController(ew.w).Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection, e.g. for a WebSocket
+// upgrade. The response is passed through without an ETag.
+func (ew *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(ew.w).Hijack()
+	if err == nil {
+		ew.mode = etagPassthrough
+		ew.buf.Reset()
+	}
+	return conn, buf, err
+}
+
+// ReadFrom copies r to the response, using the io.ReaderFrom of the
+// underlying writer (e.g. sendfile) when the body is passed through.
+func (ew *etagWriter) ReadFrom(r io.Reader) (int64, error) {
+	if ew.mode == etagUndecided {
+		ew.status = http.StatusOK
+		ew.decide()
+	}
+
+	if rf, ok := ew.w.(io.ReaderFrom); ok && ew.mode == etagPassthrough {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(writerOnly{ew}, r)
+}
+
+// Push initiates an HTTP/2 server push. It returns http.ErrNotSupported if
+// the underlying writer doesn't support it.
+func (ew *etagWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := ew.w.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying http.ResponseWriter.
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.w
+}
+
+// decide chooses how the response is handled once the status and
+// headers are known.
+func (ew *etagWriter) decide() {
+	header := ew.w.Header()
+
+	switch {
+	case ew.status != http.StatusOK,
+		strings.HasPrefix(header.Get("Content-Type"), "text/event-stream"):
+		ew.mode = etagPassthrough
+		ew.w.WriteHeader(ew.status)
+
+	case header.Get("Etag") != "":
+		if status := evalPreconditions(ew.r, header.Get("Etag"), lastModified(header)); status != 0 {
+			ew.mode = etagDiscard
+			writePreconditionStatus(ew.w, status, "")
+			return
+		}
+		ew.mode = etagPassthrough
+		ew.w.WriteHeader(ew.status)
+
+	case ew.validator != "":
+		header.Set("Etag", ew.validator)
+		ew.mode = etagPassthrough
+		ew.w.WriteHeader(ew.status)
+
+	case ew.cfg.UseTrailer || hasTrailer(header, "Etag"):
+		ew.mode = etagStreaming
+		if !hasTrailer(header, "Etag") {
+			header.Add("Trailer", "ETag")
+		}
+		header.Del("Content-Length")
+		ew.w.WriteHeader(ew.status)
+
+	default:
+		ew.mode = etagBuffering
+	}
+}
+
+// passthrough sends the buffered body and stops buffering.
+func (ew *etagWriter) passthrough() {
+	ew.mode = etagPassthrough
+	ew.w.WriteHeader(ew.status)
+	ew.w.Write(ew.buf.Bytes())
+	ew.buf.Reset()
+}
+
+// finish completes the response after the handler returns. It returns the
+// computed ETag, or "" if none was computed.
+func (ew *etagWriter) finish() string {
+	if ew.mode == etagUndecided {
+		ew.status = http.StatusOK
+		ew.decide()
+	}
+
+	switch ew.mode {
+	case etagStreaming:
+		etag := ew.format(ew.hash.Sum(nil))
+		ew.w.Header().Set("Etag", etag)
+		return etag
+
+	case etagBuffering:
+		if ew.r.Method == http.MethodHead && ew.buf.Len() == 0 {
+			if ew.etag != "" {
+				ew.w.Header().Set("Etag", ew.etag)
+			}
+			ew.w.WriteHeader(ew.status)
+			return ""
+		}
+
+		ew.hash.Write(ew.buf.Bytes())
+		etag := ew.format(ew.hash.Sum(nil))
+
+		if status := evalPreconditions(ew.r, etag, lastModified(ew.w.Header())); status != 0 {
+			writePreconditionStatus(ew.w, status, etag)
+			return etag
+		}
+
+		ew.w.Header().Set("Etag", etag)
+		ew.w.WriteHeader(ew.status)
+		ew.w.Write(ew.buf.Bytes())
+		return etag
+	}
+	return ""
+}
+
+// format returns the quoted entity tag for the checksum.
+func (ew *etagWriter) format(checksum []byte) string {
+	etag := `"` + hex.EncodeToString(checksum) + `"`
+	if ew.cfg.IsWeak {
+		return "W/" + etag
+	}
+	return etag
+}
+
+// hasTrailer reports whether the header declares the trailer.
+func hasTrailer(header http.Header, name string) bool {
+	for _, value := range header.Values("Trailer") {
+		for _, trailer := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(trailer), name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// evalPreconditions evaluates the conditional headers of the request against
+// the validators of the resource, following the order of RFC 9110 section
+// 13.2.2. It returns 304, 412 or 0 if the request should proceed.
+func evalPreconditions(r *http.Request, etag string, lastModified time.Time) int {
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
 
-			checksum := crc64.Update(0, table, buf.Bytes())
+	if ifMatch := r.Header.Values("If-Match"); len(ifMatch) > 0 {
+		if !matchETag(ifMatch, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if since, ok := parseHTTPTime(r.Header.Get("If-Unmodified-Since")); ok && !lastModified.IsZero() {
+		if lastModified.Truncate(time.Second).After(since) {
+			return http.StatusPreconditionFailed
+		}
+	}
 
-			var etag string
-			if cfg.IsWeak {
-				etag = fmt.Sprintf("W/%x", checksum)
-			} else {
-				etag = fmt.Sprintf("%x", checksum)
+	if ifNoneMatch := r.Header.Values("If-None-Match"); len(ifNoneMatch) > 0 {
+		if matchETag(ifNoneMatch, etag, true) {
+			if safe {
+				return http.StatusNotModified
 			}
+			return http.StatusPreconditionFailed
+		}
+	} else if since, ok := parseHTTPTime(r.Header.Get("If-Modified-Since")); ok && safe && !lastModified.IsZero() {
+		if !lastModified.Truncate(time.Second).After(since) {
+			return http.StatusNotModified
+		}
+	}
 
-			responseEtag := header.Get("Etag")
+	return 0
+}
 
-			if (rw.status == 0 || rw.status == http.StatusOK) && responseEtag == "" {
-				if clientEtag == etag {
-					w.WriteHeader(http.StatusNotModified)
-					return
-				}
+// matchETag reports whether any of the entity tags in the header values
+// matches the etag, using weak or strong comparison. "*" matches any
+// current representation, even when its etag is unknown, since the
+// preconditions are only evaluated for existing resources.
+func matchETag(values []string, etag string, weak bool) bool {
+	etagWeak, etagOpaque := splitETag(etag)
+	for _, value := range values {
+		for _, candidate := range strings.Split(value, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" {
+				return true
+			}
 
-				if cfg.UseCache {
-					cache.Store(uri, etag)
-				}
+			if etag == "" {
+				continue
+			}
 
-				w.Header().Set("Etag", etag)
+			candidateWeak, candidateOpaque := splitETag(candidate)
+			if candidateOpaque != etagOpaque {
+				continue
 			}
 
-			if rw.status != 0 {
-				w.WriteHeader(rw.status)
+			if weak || (!candidateWeak && !etagWeak) {
+				return true
 			}
+		}
+	}
+	return false
+}
 
-			w.Write(buf.Bytes())
-		})
+// splitETag returns whether the entity tag is weak and its opaque value
+// without quotes.
+func splitETag(etag string) (weak bool, opaque string) {
+	if rest, ok := strings.CutPrefix(etag, "W/"); ok {
+		weak, etag = true, rest
+	}
+	return weak, strings.Trim(etag, `"`)
+}
+
+// writePreconditionStatus writes a 304 or 412 response without a body.
+// The ETag is included in 304 responses, as required by RFC 9110.
+func writePreconditionStatus(w http.ResponseWriter, status int, etag string) {
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Del("Content-Type")
+	if status == http.StatusNotModified && etag != "" {
+		header.Set("Etag", etag)
+	}
+	w.WriteHeader(status)
+}
+
+// lastModified returns the time of the Last-Modified header, or the zero
+// time if it is missing or invalid.
+func lastModified(header http.Header) time.Time {
+	t, _ := parseHTTPTime(header.Get("Last-Modified"))
+	return t
+}
+
+func parseHTTPTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	t, err := http.ParseTime(value)
+	return t, err == nil
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
 	}
+	return false
 }
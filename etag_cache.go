@@ -0,0 +1,248 @@
+package httpx
+
+import (
+	"container/list"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ETagCacheConfig is the configuration for an ETagCache.
+type ETagCacheConfig struct {
+	// MaxEntries is the maximum number of ETags stored. When it is
+	// reached, the least recently used one is evicted (default: 10000).
+	MaxEntries int
+
+	// TTL is the time after which a stored ETag expires. Zero means ETags
+	// don't expire.
+	TTL time.Duration
+}
+
+var DefaultETagCacheConfig = ETagCacheConfig{
+	MaxEntries: 10000,
+}
+
+// ETagCache is a bounded LRU cache of the ETags computed by the ETag
+// middleware, keyed by request URI (path and query) as received by the
+// server, so inside a Group it includes the prefix of the group. It is safe
+// for concurrent use.
+//
+// When a response has a Vary header, an ETag is stored per variant, using
+// the values of the listed request headers (e.g. Accept-Encoding or Cookie)
+// as part of the key. Responses with "Vary: *" are not cached.
+//
+// Usage:
+//
+//	cache := httpx.NewETagCache(httpx.ETagCacheConfig{
+//		MaxEntries: 1000,
+//		TTL:        time.Hour,
+//	})
+//
+//	mux.Use(httpx.ETagWithConfig(httpx.ETagConfig{Cache: cache}))
+//
+//	mux.HandleFunc("POST /admin/posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+//		// ... update the post
+//		cache.Invalidate("/posts/" + r.PathValue("id"))
+//	})
+type ETagCache struct {
+	cfg ETagCacheConfig
+
+	mu   sync.Mutex
+	lru  *list.List
+	uris map[string]*etagCacheURI
+}
+
+// etagCacheURI holds the cached variants of a URI.
+type etagCacheURI struct {
+	vary     []string
+	variants map[string]*list.Element
+}
+
+type etagCacheEntry struct {
+	uri     string
+	variant string
+	etag    string
+	expires time.Time
+}
+
+// NewETagCache returns an empty ETagCache with the given configuration.
+func NewETagCache(cfg ETagCacheConfig) *ETagCache {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultETagCacheConfig.MaxEntries
+	}
+
+	return &ETagCache{
+		cfg:  cfg,
+		lru:  list.New(),
+		uris: make(map[string]*etagCacheURI),
+	}
+}
+
+// Invalidate removes the ETags stored for the URI, including all its
+// variants. The URI must match the request URI, including the query and
+// the prefix of the groups, e.g. "/api/users" for "/users" inside an "/api"
+// group.
+func (c *ETagCache) Invalidate(uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.uris[uri]; ok {
+		c.removeURI(uri, entry)
+	}
+}
+
+// InvalidatePrefix removes the ETags stored for all the URIs starting with
+// the prefix, e.g. "/posts/" invalidates "/posts/1" and "/posts/2?page=3".
+func (c *ETagCache) InvalidatePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for uri, entry := range c.uris {
+		if strings.HasPrefix(uri, prefix) {
+			c.removeURI(uri, entry)
+		}
+	}
+}
+
+// Len returns the number of ETags stored.
+func (c *ETagCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// get returns the ETag stored for the request, or "" if there is none.
+func (c *ETagCache) get(r *http.Request) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	uri := requestURI(r)
+	entry, ok := c.uris[uri]
+	if !ok {
+		return ""
+	}
+
+	elem, ok := entry.variants[variantKey(r, entry.vary)]
+	if !ok {
+		return ""
+	}
+
+	cached := elem.Value.(*etagCacheEntry)
+	if !cached.expires.IsZero() && time.Now().After(cached.expires) {
+		c.remove(elem)
+		return ""
+	}
+
+	c.lru.MoveToFront(elem)
+	return cached.etag
+}
+
+// set stores the ETag of the response to the request, header being the
+// response header.
+func (c *ETagCache) set(r *http.Request, header http.Header, etag string) {
+	vary := varyHeaders(header)
+	if len(vary) == 1 && vary[0] == "*" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	uri := requestURI(r)
+	entry, ok := c.uris[uri]
+	if !ok {
+		entry = &etagCacheURI{variants: make(map[string]*list.Element)}
+		c.uris[uri] = entry
+	}
+
+	// the variants stored with other Vary headers can't be looked up anymore
+	if !slices.Equal(entry.vary, vary) {
+		for _, elem := range entry.variants {
+			c.lru.Remove(elem)
+		}
+		clear(entry.variants)
+		entry.vary = vary
+	}
+
+	var expires time.Time
+	if c.cfg.TTL > 0 {
+		expires = time.Now().Add(c.cfg.TTL)
+	}
+
+	variant := variantKey(r, vary)
+	if elem, ok := entry.variants[variant]; ok {
+		cached := elem.Value.(*etagCacheEntry)
+		cached.etag, cached.expires = etag, expires
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	entry.variants[variant] = c.lru.PushFront(&etagCacheEntry{
+		uri:     uri,
+		variant: variant,
+		etag:    etag,
+		expires: expires,
+	})
+
+	for c.lru.Len() > c.cfg.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *ETagCache) remove(elem *list.Element) {
+	cached := c.lru.Remove(elem).(*etagCacheEntry)
+	entry := c.uris[cached.uri]
+	delete(entry.variants, cached.variant)
+	if len(entry.variants) == 0 {
+		delete(c.uris, cached.uri)
+	}
+}
+
+func (c *ETagCache) removeURI(uri string, entry *etagCacheURI) {
+	for _, elem := range entry.variants {
+		c.lru.Remove(elem)
+	}
+	delete(c.uris, uri)
+}
+
+// requestURI returns the path and query of the request as received by the
+// server. Unlike r.URL, it isn't modified by http.StripPrefix, so it is the
+// same inside a Group.
+func requestURI(r *http.Request) string {
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		return u.RequestURI()
+	}
+	return r.URL.RequestURI()
+}
+
+// varyHeaders returns the canonical names of the request headers listed in
+// the Vary header, or ["*"] if the response varies on anything.
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return []string{"*"}
+			}
+			if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// variantKey returns the values of the vary headers in the request,
+// separated by NUL bytes.
+func variantKey(r *http.Request, vary []string) string {
+	var b strings.Builder
+	for _, name := range vary {
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+		b.WriteByte(0)
+	}
+	return b.String()
+}
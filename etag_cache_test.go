@@ -0,0 +1,167 @@
+package httpx_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bluescreen10/httpx"
+)
+
+func TestETagCacheInvalidate(t *testing.T) {
+	cache := httpx.NewETagCache(httpx.DefaultETagCacheConfig)
+	version := "v1"
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.URL.Path, version)
+	})
+	handler := httpx.ETagWithConfig(httpx.ETagConfig{Cache: cache})(h)
+
+	get := func(uri string) string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uri, nil))
+		return w.Header().Get("ETag")
+	}
+
+	etag := get("/posts/1")
+	get("/posts/2")
+	get("/users/1")
+
+	if cache.Len() != 3 {
+		t.Fatalf("expected '3' cached etags got '%d'", cache.Len())
+	}
+
+	version = "v2"
+	cache.Invalidate("/posts/1")
+	if cache.Len() != 2 {
+		t.Fatalf("expected '2' cached etags got '%d'", cache.Len())
+	}
+
+	if got := get("/posts/1"); got == etag {
+		t.Fatalf("expected a new etag after invalidation got '%s'", got)
+	}
+
+	cache.InvalidatePrefix("/posts/")
+	if cache.Len() != 1 {
+		t.Fatalf("expected '1' cached etag got '%d'", cache.Len())
+	}
+}
+
+func TestETagCacheInvalidateGroup(t *testing.T) {
+	cache := httpx.NewETagCache(httpx.DefaultETagCacheConfig)
+
+	mux := httpx.NewServeMux()
+	api := mux.Group("/api", httpx.ETagWithConfig(httpx.ETagConfig{Cache: cache}))
+	api.HandleFunc("GET /users", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("users"))
+	})
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	if cache.Len() != 1 {
+		t.Fatalf("expected '1' cached etag got '%d'", cache.Len())
+	}
+
+	// the full request URI is used, not the one stripped by the group
+	cache.Invalidate("/api/users")
+	if cache.Len() != 0 {
+		t.Fatalf("expected '0' cached etags got '%d'", cache.Len())
+	}
+}
+
+func TestETagCacheEviction(t *testing.T) {
+	cache := httpx.NewETagCache(httpx.ETagCacheConfig{MaxEntries: 2})
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	})
+	handler := httpx.ETagWithConfig(httpx.ETagConfig{Cache: cache})(h)
+
+	for _, uri := range []string{"/a", "/b", "/a", "/c"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, uri, nil))
+	}
+
+	if cache.Len() != 2 {
+		t.Fatalf("expected '2' cached etags got '%d'", cache.Len())
+	}
+
+	// "/b" was the least recently used, so it is evicted and the request
+	// goes to the handler
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/b", nil)
+	r.Header.Set("If-Match", `"stale"`)
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status '%d' got '%d'", http.StatusOK, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, "/a", nil)
+	r.Header.Set("If-Match", `"stale"`)
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected status '%d' got '%d'", http.StatusPreconditionFailed, w.Code)
+	}
+}
+
+func TestETagCacheTTL(t *testing.T) {
+	cache := httpx.NewETagCache(httpx.ETagCacheConfig{TTL: 10 * time.Millisecond})
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	})
+	handler := httpx.ETagWithConfig(httpx.ETagConfig{Cache: cache})(h)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	time.Sleep(20 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/", nil)
+	r.Header.Set("If-Match", `"stale"`)
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected expired etag and status '%d' got '%d'", http.StatusOK, w.Code)
+	}
+
+	if cache.Len() != 0 {
+		t.Fatalf("expected '0' cached etags got '%d'", cache.Len())
+	}
+}
+
+func TestETagCacheVary(t *testing.T) {
+	cache := httpx.NewETagCache(httpx.DefaultETagCacheConfig)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Accept-Language")
+		fmt.Fprintf(w, "hello %s", r.Header.Get("Accept-Language"))
+	})
+	handler := httpx.ETagWithConfig(httpx.ETagConfig{Cache: cache})(h)
+
+	etags := map[string]string{}
+	for _, lang := range []string{"en", "es"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", lang)
+		handler.ServeHTTP(w, r)
+		etags[lang] = w.Header().Get("ETag")
+	}
+
+	if cache.Len() != 2 {
+		t.Fatalf("expected '2' cached etags got '%d'", cache.Len())
+	}
+
+	// the cached etag of the "es" variant must be used
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/", nil)
+	r.Header.Set("Accept-Language", "es")
+	r.Header.Set("If-Match", etags["en"])
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected status '%d' got '%d'", http.StatusPreconditionFailed, w.Code)
+	}
+}
@@ -2,19 +2,24 @@ package httpx_test
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"hash/crc64"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/bluescreen10/httpx"
 )
 
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
 func TestGenerateETag(t *testing.T) {
 	body := []byte("hello world")
-	crc := crc64.Checksum(body, crc64.MakeTable(crc64.ECMA))
-	expectedEtag := fmt.Sprintf("%x", crc)
+	crc := crc64.Checksum(body, crc64Table)
+	expectedEtag := fmt.Sprintf(`"%016x"`, crc)
 
 	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -34,8 +39,8 @@ func TestGenerateETag(t *testing.T) {
 
 func TestNotModified(t *testing.T) {
 	body := []byte("hello world")
-	crc := crc64.Checksum(body, crc64.MakeTable(crc64.ECMA))
-	reqEtag := fmt.Sprintf("%x", crc)
+	crc := crc64.Checksum(body, crc64Table)
+	reqEtag := fmt.Sprintf(`"%016x"`, crc)
 
 	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -56,8 +61,8 @@ func TestNotModified(t *testing.T) {
 
 func TestEtagCache(t *testing.T) {
 	body := []byte("hello world")
-	crc := crc64.Checksum(body, crc64.MakeTable(crc64.ECMA))
-	reqEtag := fmt.Sprintf("%x", crc)
+	crc := crc64.Checksum(body, crc64Table)
+	reqEtag := fmt.Sprintf(`"%016x"`, crc)
 
 	count := 0
 	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -85,8 +90,8 @@ func TestEtagCache(t *testing.T) {
 
 func TestGenerateWeakETag(t *testing.T) {
 	body := []byte("hello world")
-	crc := crc64.Checksum(body, crc64.MakeTable(crc64.ECMA))
-	expectedEtag := fmt.Sprintf("W/%x", crc)
+	crc := crc64.Checksum(body, crc64Table)
+	expectedEtag := fmt.Sprintf(`W/"%016x"`, crc)
 
 	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -143,3 +148,357 @@ func TestGenerateSkipETagOnMethod(t *testing.T) {
 		t.Fatalf("ETag expected '' header but got '%s'", got)
 	}
 }
+
+func TestETagMaxBufferSize(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 100)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body[:50])
+		w.Write(body[50:])
+	})
+
+	etag := httpx.ETagWithConfig(httpx.ETagConfig{MaxBufferSize: 64})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", &bytes.Buffer{})
+	etag(h).ServeHTTP(w, r)
+
+	if got := w.Header().Get("ETag"); got != "" {
+		t.Fatalf("ETag expected '' header but got '%s'", got)
+	}
+
+	if !bytes.Equal(w.Body.Bytes(), body) {
+		t.Fatalf("expected body to be passed through got '%s'", w.Body.String())
+	}
+}
+
+func TestETagEventStream(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+
+		// the event must reach the client before the handler returns
+		if rec := w.(interface{ Unwrap() http.ResponseWriter }).Unwrap().(*httptest.ResponseRecorder); rec.Body.Len() == 0 {
+			t.Fatal("expected event to be written without buffering")
+		}
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", &bytes.Buffer{})
+	httpx.ETag()(h).ServeHTTP(w, r)
+
+	if got := w.Header().Get("ETag"); got != "" {
+		t.Fatalf("ETag expected '' header but got '%s'", got)
+	}
+}
+
+func TestETagTrailer(t *testing.T) {
+	body := []byte("hello world")
+	crc := crc64.Checksum(body, crc64Table)
+	expectedEtag := fmt.Sprintf(`"%016x"`, crc)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "ETag")
+		w.Write(body[:5])
+		w.Write(body[5:])
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", &bytes.Buffer{})
+	httpx.ETag()(h).ServeHTTP(w, r)
+
+	res := w.Result()
+	if got := res.Trailer.Get("ETag"); got != expectedEtag {
+		t.Fatalf("ETag trailer expected '%s' but got '%s'", expectedEtag, got)
+	}
+
+	if w.Body.String() != string(body) {
+		t.Fatalf("expected body '%s' got '%s'", body, w.Body.String())
+	}
+}
+
+func TestETagDeclared(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", "v1")
+		w.Write([]byte("hello world"))
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", &bytes.Buffer{})
+	r.Header.Set("If-None-Match", "v1")
+	httpx.ETag()(h).ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("expected status 304 without body but got %d '%s'", w.Code, w.Body.String())
+	}
+}
+
+func TestETagPreconditions(t *testing.T) {
+	body := []byte("hello world")
+	crc := crc64.Checksum(body, crc64Table)
+	etag := fmt.Sprintf(`"%016x"`, crc)
+	modified := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		w.Write(body)
+	})
+
+	tests := []struct {
+		header string
+		value  string
+		status int
+	}{
+		{"If-None-Match", `"other", ` + etag, http.StatusNotModified},
+		{"If-None-Match", "W/" + etag, http.StatusNotModified},
+		{"If-None-Match", "*", http.StatusNotModified},
+		{"If-None-Match", `"other"`, http.StatusOK},
+		{"If-Match", `"other"`, http.StatusPreconditionFailed},
+		{"If-Match", etag, http.StatusOK},
+		{"If-Modified-Since", modified.Format(http.TimeFormat), http.StatusNotModified},
+		{"If-Modified-Since", modified.Add(-time.Hour).Format(http.TimeFormat), http.StatusOK},
+		{"If-Unmodified-Since", modified.Add(-time.Hour).Format(http.TimeFormat), http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", &bytes.Buffer{})
+		r.Header.Set(tt.header, tt.value)
+		httpx.ETag()(h).ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Fatalf("%s: %s expected status '%d' got '%d'", tt.header, tt.value, tt.status, w.Code)
+		}
+	}
+}
+
+func TestETagIfMatchUnsafe(t *testing.T) {
+	body := []byte("hello world")
+	crc := crc64.Checksum(body, crc64Table)
+	etag := fmt.Sprintf(`"%016x"`, crc)
+
+	updates := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			updates++
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write(body)
+	})
+
+	handler := httpx.ETagWithConfig(httpx.ETagConfig{UseCache: true})(h)
+
+	// populate the cache
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/resource", &bytes.Buffer{})
+	handler.ServeHTTP(w, r)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, "/resource", &bytes.Buffer{})
+	r.Header.Set("If-Match", `"stale"`)
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusPreconditionFailed || updates != 0 {
+		t.Fatalf("expected status 412 without update got '%d' and '%d' updates", w.Code, updates)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, "/resource", &bytes.Buffer{})
+	r.Header.Set("If-Match", etag)
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent || updates != 1 {
+		t.Fatalf("expected status 204 with update got '%d' and '%d' updates", w.Code, updates)
+	}
+}
+
+func TestETagHead(t *testing.T) {
+	body := []byte("hello world")
+	crc := crc64.Checksum(body, crc64Table)
+	expectedEtag := fmt.Sprintf(`"%016x"`, crc)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodHead, "/", nil)
+	httpx.ETag()(h).ServeHTTP(w, r)
+
+	if got := w.Header().Get("ETag"); got != expectedEtag {
+		t.Fatalf("ETag expected '%s' header but got '%s'", expectedEtag, got)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodHead, "/", nil)
+	r.Header.Set("If-None-Match", expectedEtag)
+	httpx.ETag()(h).ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected status '%d' got '%d'", http.StatusNotModified, w.Code)
+	}
+}
+
+func TestETagHeadWithoutBody(t *testing.T) {
+	body := []byte("hello world")
+	crc := crc64.Checksum(body, crc64Table)
+	expectedEtag := fmt.Sprintf(`"%016x"`, crc)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			w.Write(body)
+		}
+	})
+
+	handler := httpx.ETagWithConfig(httpx.ETagConfig{UseCache: true})(h)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodHead, "/", nil)
+	handler.ServeHTTP(w, r)
+
+	if got := w.Header().Get("ETag"); got != "" {
+		t.Fatalf("expected no ETag header but got '%s'", got)
+	}
+
+	// populate the cache
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodHead, "/", nil)
+	handler.ServeHTTP(w, r)
+
+	if got := w.Header().Get("ETag"); got != expectedEtag {
+		t.Fatalf("ETag expected '%s' header but got '%s'", expectedEtag, got)
+	}
+}
+
+func TestETagHasher(t *testing.T) {
+	body := []byte("hello world")
+	sum := sha256.Sum256(body)
+	expectedEtag := fmt.Sprintf(`"%x"`, sum)
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	httpx.ETagWithConfig(httpx.ETagConfig{Hasher: httpx.ETagSHA256})(h).ServeHTTP(w, r)
+
+	if got := w.Header().Get("ETag"); got != expectedEtag {
+		t.Fatalf("ETag expected '%s' header but got '%s'", expectedEtag, got)
+	}
+}
+
+func TestETagValidatorsRoundTrip(t *testing.T) {
+	version := 1
+	validators := func(r *http.Request) (string, time.Time) {
+		return fmt.Sprintf(`"v%d"`, version), time.Time{}
+	}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			version++
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte("hello world"))
+	})
+
+	handler := httpx.ETagWithConfig(httpx.ETagConfig{Validators: validators})(h)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/resource", nil)
+	handler.ServeHTTP(w, r)
+
+	etag := w.Header().Get("ETag")
+	if etag != `"v1"` || w.Body.String() != "hello world" {
+		t.Fatalf("expected ETag '\"v1\"' and body 'hello world' got '%s' and '%s'", etag, w.Body.String())
+	}
+
+	// the ETag given to the client is accepted by If-Match
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, "/resource", nil)
+	r.Header.Set("If-Match", etag)
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status '%d' got '%d'", http.StatusNoContent, w.Code)
+	}
+
+	// and rejected once the resource changed
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, "/resource", nil)
+	r.Header.Set("If-Match", etag)
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected status '%d' got '%d'", http.StatusPreconditionFailed, w.Code)
+	}
+}
+
+func TestETagIfMatchAny(t *testing.T) {
+	modified := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	validators := func(r *http.Request) (string, time.Time) {
+		return "", modified
+	}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	handler := httpx.ETagWithConfig(httpx.ETagConfig{Validators: validators})(h)
+
+	// the resource exists, even if its ETag is unknown
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/resource", nil)
+	r.Header.Set("If-Match", "*")
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected status '%d' got '%d'", http.StatusNoContent, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, "/resource", nil)
+	r.Header.Set("If-None-Match", "*")
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected status '%d' got '%d'", http.StatusPreconditionFailed, w.Code)
+	}
+}
+
+func TestETagHijack(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("expected writer to implement http.Hijacker")
+			return
+		}
+
+		conn, buf, err := hijacker.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		buf.Flush()
+	})
+
+	ts := httptest.NewServer(httpx.ETag()(h))
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "hijacked" || res.Header.Get("ETag") != "" {
+		t.Fatalf("expected body 'hijacked' without ETag got '%s'", body)
+	}
+}
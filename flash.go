@@ -0,0 +1,112 @@
+package httpx
+
+import (
+	"encoding/gob"
+	"fmt"
+	"html/template"
+	"net/http"
+	"slices"
+)
+
+// FlashKind is the category of a flash message, usually used to style it.
+type FlashKind string
+
+const (
+	FlashSuccess FlashKind = "success"
+	FlashError   FlashKind = "error"
+	FlashInfo    FlashKind = "info"
+)
+
+// Flash is a one-shot message stored in the session, to be shown on the
+// next page, e.g. after a post/redirect/get.
+type Flash struct {
+	Kind    FlashKind
+	Message string
+}
+
+// flashesKey is the session key where the pending flashes are stored.
+const flashesKey = "_flashes"
+
+func init() {
+	gob.Register([]Flash{})
+}
+
+// Flash adds a flash message of the given kind to the session.
+//
+// Usage:
+//
+//	mux.HandleFunc("POST /posts", func(w http.ResponseWriter, r *http.Request) {
+//		// ... create the post
+//		sm.Get(r).Flash(httpx.FlashSuccess, "Post created")
+//		http.Redirect(w, r, "/posts", http.StatusSeeOther)
+//	})
+func (s *Session) Flash(kind FlashKind, msg string) {
+	flashes, _ := s.values[flashesKey].([]Flash)
+	s.Set(flashesKey, append(flashes, Flash{Kind: kind, Message: msg}))
+}
+
+// Flashes returns the pending flash messages of the given kinds, or all of
+// them if no kind is given, and removes them from the session.
+func (s *Session) Flashes(kinds ...FlashKind) []Flash {
+	flashes, _ := s.values[flashesKey].([]Flash)
+	if len(flashes) == 0 {
+		return nil
+	}
+
+	var matched, pending []Flash
+	for _, flash := range flashes {
+		if len(kinds) == 0 || slices.Contains(kinds, flash.Kind) {
+			matched = append(matched, flash)
+		} else {
+			pending = append(pending, flash)
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil
+	}
+
+	if len(pending) == 0 {
+		s.Delete(flashesKey)
+	} else {
+		s.Set(flashesKey, pending)
+	}
+	return matched
+}
+
+// FuncMap returns the template functions provided by the SessionManager, so
+// they can be registered on a Renderer. It includes "flashes" which returns
+// and removes the pending flash messages of the session, optionally
+// filtered by kind. It takes the request or the session:
+//
+//	renderer.Funcs(sm.FuncMap())
+//
+//	renderer.Html(w, "posts", httpx.Vals{"Request": r})
+//
+//	{{ range flashes .Request "error" }}
+//		<div class="alert alert-{{ .Kind }}">{{ .Message }}</div>
+//	{{ end }}
+//
+// As the flashes are removed from the session while rendering, the template
+// must be rendered before writing the response, like Renderer.Html does.
+func (m *SessionManager) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"flashes": func(v any, kinds ...string) ([]Flash, error) {
+			var sess *Session
+			switch v := v.(type) {
+			case *http.Request:
+				sess = m.Get(v)
+			case *Session:
+				sess = v
+			default:
+				return nil, fmt.Errorf("flashes: expected *http.Request or *Session, got %T", v)
+			}
+
+			flashKinds := make([]FlashKind, len(kinds))
+			for i, kind := range kinds {
+				flashKinds[i] = FlashKind(kind)
+			}
+			return sess.Flashes(flashKinds...), nil
+		},
+	}
+}
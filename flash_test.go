@@ -0,0 +1,84 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/bluescreen10/httpx"
+)
+
+func TestFlashes(t *testing.T) {
+	store, _ := newMapStore()
+	sm := httpx.NewSessionManager(store)
+
+	h1 := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess := sm.Get(r)
+		sess.Flash(httpx.FlashSuccess, "saved")
+		sess.Flash(httpx.FlashError, "failed")
+		sess.Flash(httpx.FlashInfo, "note")
+	})
+
+	w1 := httptest.NewRecorder()
+	sm.Handler(h1).ServeHTTP(w1, httptest.NewRequest("POST", "/", nil))
+	cookie := w1.Result().Cookies()[0]
+
+	h2 := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess := sm.Get(r)
+		errs := sess.Flashes(httpx.FlashError)
+		if len(errs) != 1 || errs[0].Message != "failed" {
+			t.Fatalf("expected error flash 'failed' got '%v'", errs)
+		}
+
+		if errs := sess.Flashes(httpx.FlashError); len(errs) != 0 {
+			t.Fatalf("expected no error flashes got '%v'", errs)
+		}
+	})
+
+	r2 := httptest.NewRequest("GET", "/", nil)
+	r2.AddCookie(cookie)
+	sm.Handler(h2).ServeHTTP(httptest.NewRecorder(), r2)
+
+	h3 := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flashes := sm.Get(r).Flashes()
+		if len(flashes) != 2 || flashes[0].Kind != httpx.FlashSuccess || flashes[1].Kind != httpx.FlashInfo {
+			t.Fatalf("expected success and info flashes got '%v'", flashes)
+		}
+	})
+
+	r3 := httptest.NewRequest("GET", "/", nil)
+	r3.AddCookie(cookie)
+	sm.Handler(h3).ServeHTTP(httptest.NewRecorder(), r3)
+}
+
+func TestFlashesFunc(t *testing.T) {
+	store, _ := newMapStore()
+	sm := httpx.NewSessionManager(store)
+
+	renderer := httpx.NewRenderer(fstest.MapFS{
+		"index.html": {Data: []byte(`{{ range flashes .Request "error" }}{{ .Kind }}:{{ .Message }}{{ end }}`)},
+	}, ".html")
+	renderer.Funcs(sm.FuncMap())
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess := sm.Get(r)
+		sess.Flash(httpx.FlashError, "failed")
+		sess.Flash(httpx.FlashInfo, "note")
+
+		if err := renderer.Html(w, "index", httpx.Vals{"Request": r}); err != nil {
+			t.Fatal(err)
+		}
+
+		if flashes := sess.Flashes(); len(flashes) != 1 || flashes[0].Kind != httpx.FlashInfo {
+			t.Fatalf("expected pending info flash got '%v'", flashes)
+		}
+	})
+
+	w := httptest.NewRecorder()
+	sm.Handler(h).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if body := w.Body.String(); body != "error:failed" {
+		t.Fatalf("expected body 'error:failed' got '%s'", body)
+	}
+}
@@ -6,6 +6,7 @@
 package gormstore
 
 import (
+	"context"
 	"log"
 	"time"
 
@@ -36,8 +37,13 @@ func New(db *gorm.DB) (*GORMStore, error) {
 // the data, a boolean indicating whether the token was found and
 // not expired, and an error.
 func (s *GORMStore) Get(token string) ([]byte, bool, error) {
+	return s.GetCtx(context.Background(), token)
+}
+
+// GetCtx is like Get, using the context for the query.
+func (s *GORMStore) GetCtx(ctx context.Context, token string) ([]byte, bool, error) {
 	sess := &session{}
-	tx := s.db.Where("token = ? AND expires_at >= ?", token, time.Now()).Limit(1).Find(sess)
+	tx := s.db.WithContext(ctx).Where("token = ? AND expires_at >= ?", token, time.Now()).Limit(1).Find(sess)
 	if tx.Error != nil || tx.RowsAffected == 0 {
 		return nil, false, tx.Error
 	}
@@ -49,14 +55,24 @@ func (s *GORMStore) Get(token string) ([]byte, bool, error) {
 // a record with the same token already exists, it is overwritten. The
 // expiresAt parameter specifies when the record should be considered expired.
 func (s *GORMStore) Set(token string, data []byte, expiresAt time.Time) error {
+	return s.SetCtx(context.Background(), token, data, expiresAt)
+}
+
+// SetCtx is like Set, using the context for the query.
+func (s *GORMStore) SetCtx(ctx context.Context, token string, data []byte, expiresAt time.Time) error {
 	sess := &session{}
-	tx := s.db.Where(session{Token: token}).Assign(session{Data: data, ExpiresAt: expiresAt}).FirstOrCreate(sess)
+	tx := s.db.WithContext(ctx).Where(session{Token: token}).Assign(session{Data: data, ExpiresAt: expiresAt}).FirstOrCreate(sess)
 	return tx.Error
 }
 
 // Delete removes the data associated with the given token.
 func (s *GORMStore) Delete(token string) error {
-	tx := s.db.Delete(&session{}, "token = ?", token)
+	return s.DeleteCtx(context.Background(), token)
+}
+
+// DeleteCtx is like Delete, using the context for the query.
+func (s *GORMStore) DeleteCtx(ctx context.Context, token string) error {
+	tx := s.db.WithContext(ctx).Delete(&session{}, "token = ?", token)
 	return tx.Error
 }
 
@@ -21,13 +21,17 @@
 //
 // Only responses with "Content-Type: text/html" and a closing </body>
 // tag will be modified to inject the script. Non-HTML responses pass
-// through unmodified. Client communication is done via Server-Sent Events (SSE).
+// through unmodified and unbuffered, as do responses flushed or hijacked
+// by the handler. Client communication is done via Server-Sent Events (SSE).
 package httpx
 
 import (
+	"bufio"
 	"bytes"
 	_ "embed"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -76,32 +80,131 @@ func (lr *LiveReload) Handler(next http.Handler) http.Handler {
 			return
 		}
 
-		buf := &bytes.Buffer{}
-		rw := newResponseWriter(buf, w.Header(), nil)
-		next.ServeHTTP(rw, r)
-
-		body := buf.Bytes()
-		closingBodyAt := bytes.Index(body, []byte("</body>"))
-		contentType := rw.Header().Get("Content-Type")
-		validContentType := contentType == "" || strings.Contains(contentType, "text/html")
-
-		if validContentType && closingBodyAt != -1 {
-			w.Header().Set("Content-Length", strconv.Itoa(len(body)+len(js)))
-			if rw.status != 0 {
-				w.WriteHeader(rw.status)
-			}
-			w.Write(body[:closingBodyAt])
-			w.Write(js)
-			w.Write(body[closingBodyAt:])
-		} else {
-			if rw.status != 0 {
-				w.WriteHeader(rw.status)
-			}
-			w.Write(body)
-		}
+		lw := &liveReloadWriter{ResponseWriter: NewResponseWriter(w)}
+		next.ServeHTTP(lw, r)
+		lw.finish(js)
 	})
 }
 
+// liveReloadWriter buffers HTML responses to inject the script before the
+// closing </body> tag. Other responses, and responses flushed or hijacked by
+// the handler (e.g. Server-Sent Events or WebSockets), are passed through.
+type liveReloadWriter struct {
+	*ResponseWriter
+	status      int
+	buf         bytes.Buffer
+	decided     bool
+	passthrough bool
+}
+
+var (
+	_ http.Flusher  = &liveReloadWriter{}
+	_ http.Hijacker = &liveReloadWriter{}
+	_ io.ReaderFrom = &liveReloadWriter{}
+)
+
+// decide chooses whether the response is buffered once the headers are
+// known. Responses without a Content-Type are assumed to be HTML.
+func (lw *liveReloadWriter) decide(status int) {
+	lw.decided = true
+	lw.status = status
+
+	contentType := lw.Header().Get("Content-Type")
+	if contentType != "" && !strings.Contains(contentType, "text/html") {
+		lw.stopBuffering()
+	}
+}
+
+// stopBuffering sends the header and the buffered body, and passes the
+// rest of the response through.
+func (lw *liveReloadWriter) stopBuffering() {
+	if lw.passthrough {
+		return
+	}
+
+	lw.passthrough = true
+	if lw.status != 0 {
+		lw.ResponseWriter.WriteHeader(lw.status)
+	}
+	if lw.buf.Len() > 0 {
+		lw.ResponseWriter.Write(lw.buf.Bytes())
+		lw.buf.Reset()
+	}
+}
+
+func (lw *liveReloadWriter) WriteHeader(status int) {
+	if status < 200 || lw.passthrough {
+		lw.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	if !lw.decided {
+		lw.decide(status)
+	}
+}
+
+func (lw *liveReloadWriter) Write(data []byte) (int, error) {
+	if !lw.decided {
+		lw.decide(http.StatusOK)
+	}
+
+	if lw.passthrough {
+		return lw.ResponseWriter.Write(data)
+	}
+	return lw.buf.Write(data)
+}
+
+func (lw *liveReloadWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !lw.decided {
+		lw.decide(http.StatusOK)
+	}
+
+	if lw.passthrough {
+		return lw.ResponseWriter.ReadFrom(r)
+	}
+	return lw.buf.ReadFrom(r)
+}
+
+// Flush stops buffering the response, since the handler wants the data
+// sent as soon as possible, and flushes the underlying writer.
+func (lw *liveReloadWriter) Flush() {
+	if !lw.decided {
+		lw.decide(http.StatusOK)
+	}
+
+	lw.stopBuffering()
+	lw.ResponseWriter.Flush()
+}
+
+// Hijack lets the caller take over the connection, nothing is injected.
+func (lw *liveReloadWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	lw.decided, lw.passthrough = true, true
+	return lw.ResponseWriter.Hijack()
+}
+
+// finish sends the buffered response, with the script injected if it is a
+// complete HTML page.
+func (lw *liveReloadWriter) finish(js []byte) {
+	if lw.passthrough {
+		return
+	}
+
+	body := lw.buf.Bytes()
+	closingBodyAt := bytes.Index(body, []byte("</body>"))
+	if closingBodyAt == -1 {
+		lw.stopBuffering()
+		return
+	}
+
+	lw.Header().Set("Content-Length", strconv.Itoa(len(body)+len(js)))
+	if lw.status != 0 {
+		lw.ResponseWriter.WriteHeader(lw.status)
+	}
+	lw.ResponseWriter.Write(body[:closingBodyAt])
+	lw.ResponseWriter.Write(js)
+	lw.ResponseWriter.Write(body[closingBodyAt:])
+}
+
 // Reload will trigger a reload of the current page in the browser.
 // This can be used in combination with file watcher to force a page
 // reload.
@@ -1,6 +1,7 @@
 package httpx_test
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
@@ -222,3 +223,33 @@ func TestLiveReloadSSEReload(t *testing.T) {
 		t.Fatalf("expected second timestamp '%s' to be greather than the first '%s'", ts2, ts1)
 	}
 }
+
+func TestLiveReloadEventStream(t *testing.T) {
+	done := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: hello\n\n")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Error(err)
+			return
+		}
+		<-done
+	})
+
+	lr := httpx.NewLiveReload()
+	ts := httptest.NewServer(lr.Handler(handler))
+	defer ts.Close()
+	defer close(done)
+
+	res, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	// the event arrives while the handler is still running
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	if err != nil || line != "data: hello\n" {
+		t.Fatalf("expected event 'data: hello' got '%s'", line)
+	}
+}
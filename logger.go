@@ -2,7 +2,9 @@
 // It allows customizable log formats and output destinations.
 //
 // Log entries can include variables such as time, HTTP status, latency,
-// client IP, request method, request path, and error (currently unused).
+// client IP, request method, request path, and the error returned by
+// handlers using HandlerFunc or reported with WriteError. See LoggerConfig
+// for the full list of placeholders.
 //
 // Usage:
 //
@@ -21,13 +23,25 @@
 //
 // The middleware wraps the http.Handler, recording request start time,
 // status code, latency, client IP, HTTP method, and path. Log entries
-// are written to the configured output, defaulting to os.Stdout.
+// are written to the configured output, defaulting to os.Stdout. The
+// client IP is the one resolved by ClientIPResolver.Handler when it runs
+// before the Logger, or the host of the remote address otherwise.
+//
+// For structured logging, set Logger (or Handler) and every request is
+// emitted as a slog.Record with typed attributes instead:
+//
+//	logger := httpx.LoggerWithConfig(httpx.LoggerConfig{
+//		Logger: slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+//	})
 package httpx
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
-	"net"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"os"
 	"strconv"
@@ -36,8 +50,109 @@ import (
 )
 
 type LoggerConfig struct {
+	// Format is the template of each log entry. The supported
+	// placeholders are:
+	//
+	//	${time}          start time of the request, see TimeFormat
+	//	${status}        response status code
+	//	${latency}       time taken to serve the request (e.g. "1.2ms")
+	//	${latency_ms}    latency in milliseconds (e.g. "1.200")
+	//	${ip}            client IP
+	//	${method}        request method
+	//	${path}          request path
+	//	${query}         raw query string
+	//	${uri}           request URI, path and query
+	//	${proto}         protocol, e.g. "HTTP/1.1"
+	//	${host}          request host
+	//	${referer}       Referer header
+	//	${user_agent}    User-Agent header
+	//	${bytes_in}      bytes read from the request body
+	//	${bytes_out}     bytes written to the response body
+	//	${error}         error returned by the handler
+	//	${header:X-Name} value of the X-Name request header
+	//	${cookie:name}   value of the name cookie
+	//
+	// Unknown placeholders are written as is. The format is compiled once
+	// when the middleware is created.
 	Format string
 	Output io.Writer
+
+	// TimeFormat is the layout used for ${time} (default: time.DateTime).
+	TimeFormat string
+
+	// Escape, if set, is applied to the value of every placeholder, so
+	// values can't break the structure of the format. See LogEscapeCLF
+	// and LogEscapeJSON.
+	Escape func(string) string
+
+	// Logger, if set, receives every request as a slog.Record instead of
+	// writing Format to Output. The level is Error for 5xx responses, Warn
+	// for 4xx and Info otherwise.
+	Logger *slog.Logger
+
+	// Handler is like Logger but takes a slog.Handler directly. It is
+	// ignored if Logger is set.
+	Handler slog.Handler
+
+	// Skip lists rules for requests that should not be logged. A request
+	// is skipped if any of them returns true. See LogSkipPath,
+	// LogSkipMethod, LogSkipStatus and LogSample.
+	//
+	//	cfg.Skip = []httpx.LogSkipper{
+	//		httpx.LogSkipPath("/health", "/_livereload"),
+	//		httpx.LogSample(map[int]float64{2: 0.1}), // 10% of 2xx
+	//	}
+	Skip []LogSkipper
+}
+
+// LogSkipper reports whether a request should not be logged. It is called
+// after the request has been served with the response status.
+type LogSkipper func(r *http.Request, status int) bool
+
+// LogSkipPath skips requests whose path starts with any of the prefixes.
+func LogSkipPath(prefixes ...string) LogSkipper {
+	return func(r *http.Request, _ int) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// LogSkipMethod skips requests with any of the methods.
+func LogSkipMethod(methods ...string) LogSkipper {
+	return func(r *http.Request, _ int) bool {
+		for _, method := range methods {
+			if r.Method == method {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// LogSkipStatus skips responses with a status between low and high, both
+// included.
+func LogSkipStatus(low, high int) LogSkipper {
+	return func(_ *http.Request, status int) bool {
+		return status >= low && status <= high
+	}
+}
+
+// LogSample samples requests by status class. The rates map a class (2 for
+// 2xx, 3 for 3xx...) to the fraction of requests that are logged, between 0
+// and 1. Classes without a rate are always logged, so errors can be kept
+// while only a sample of the successful requests is logged.
+func LogSample(rates map[int]float64) LogSkipper {
+	return func(_ *http.Request, status int) bool {
+		rate, ok := rates[status/100]
+		if !ok {
+			return false
+		}
+		return rand.Float64() >= rate
+	}
 }
 
 var DefaultLoggerConfig = LoggerConfig{
@@ -45,6 +160,36 @@ var DefaultLoggerConfig = LoggerConfig{
 	Output: os.Stdout,
 }
 
+// clfTimeFormat is the time layout of the NCSA log formats.
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// CommonLoggerConfig logs requests in the NCSA Common Log Format.
+var CommonLoggerConfig = LoggerConfig{
+	Format:     "${ip} - - [${time}] \"${method} ${uri} ${proto}\" ${status} ${bytes_out}\n",
+	TimeFormat: clfTimeFormat,
+	Escape:     LogEscapeCLF,
+	Output:     os.Stdout,
+}
+
+// CombinedLoggerConfig logs requests in the NCSA Combined Log Format, which
+// is the Common Log Format followed by the referer and user agent.
+var CombinedLoggerConfig = LoggerConfig{
+	Format:     "${ip} - - [${time}] \"${method} ${uri} ${proto}\" ${status} ${bytes_out} \"${referer}\" \"${user_agent}\"\n",
+	TimeFormat: clfTimeFormat,
+	Escape:     LogEscapeCLF,
+	Output:     os.Stdout,
+}
+
+// JSONLoggerConfig logs requests as JSON lines, one object per request.
+var JSONLoggerConfig = LoggerConfig{
+	Format: `{"time":"${time}","status":${status},"latency_ms":${latency_ms},"ip":"${ip}",` +
+		`"method":"${method}","uri":"${uri}","proto":"${proto}","host":"${host}","bytes_in":${bytes_in},` +
+		`"bytes_out":${bytes_out},"referer":"${referer}","user_agent":"${user_agent}","error":"${error}"}` + "\n",
+	TimeFormat: time.RFC3339Nano,
+	Escape:     LogEscapeJSON,
+	Output:     os.Stdout,
+}
+
 // Logger returns a middleware with the default configuration. It logs
 // requests using the configured format and output. It records start time,
 // response status code, latency, client IP, HTTP method, and path.
@@ -54,31 +199,307 @@ func Logger() Middleware {
 
 // LoggerWithConfig returns a Logger middleware with the specified configuration.
 func LoggerWithConfig(cfg LoggerConfig) Middleware {
+	handler := cfg.Handler
+	if cfg.Logger != nil {
+		handler = cfg.Logger.Handler()
+	}
+
+	if cfg.TimeFormat == "" {
+		cfg.TimeFormat = time.DateTime
+	}
+	format := compileLogFormat(cfg.Format, cfg.TimeFormat, cfg.Escape)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()
-			rw := newResponseWriter(w, w.Header(), w.WriteHeader)
+			entry := &logEntry{start: time.Now()}
+			rw := NewResponseWriter(w)
+			r, slot := withErrorSlot(r)
+
+			var body *countingReader
+			if r.Body != nil && r.Body != http.NoBody {
+				body = &countingReader{ReadCloser: r.Body}
+				r = withBody(r, body)
+			}
+
 			next.ServeHTTP(rw, r)
 
-			latency := time.Since(start)
-			ip, _, _ := net.SplitHostPort(r.RemoteAddr)
+			entry.r = r
+			entry.latency = time.Since(entry.start)
+			entry.ip = ClientIP(r)
+			entry.bytesOut = rw.Size()
+			entry.err = slot.err
+
+			entry.status = 200
+			if rw.Written() {
+				entry.status = rw.Status()
+			}
+
+			if body != nil {
+				entry.bytesIn = body.n
+			}
+
+			for _, skip := range cfg.Skip {
+				if skip(r, entry.status) {
+					return
+				}
+			}
 
-			status := 200
-			if rw.status != 0 {
-				status = rw.status
+			if handler != nil {
+				logRecord(handler, entry)
+				return
 			}
 
-			replacer := strings.NewReplacer(
-				"${time}", start.Format(time.DateTime),
-				"${status}", strconv.Itoa(status),
-				"${latency}", latency.String(),
-				"${ip}", ip,
-				"${method}", r.Method,
-				"${path}", r.URL.Path,
-				"${error}", "", // not sure how to do this.
-			)
-
-			fmt.Fprint(cfg.Output, replacer.Replace(cfg.Format))
+			buf := buffers.Get().(*bytes.Buffer)
+			defer buffers.Put(buf)
+			buf.Reset()
+
+			format.write(buf, entry)
+			cfg.Output.Write(buf.Bytes())
 		})
 	}
 }
+
+// logEntry holds the information about a request that is logged.
+type logEntry struct {
+	r        *http.Request
+	start    time.Time
+	latency  time.Duration
+	status   int
+	bytesIn  int64
+	bytesOut int64
+	ip       string
+	err      error
+}
+
+// logRecord emits the request as a slog.Record through the handler.
+func logRecord(handler slog.Handler, e *logEntry) {
+	level := slog.LevelInfo
+	switch {
+	case e.status >= 500:
+		level = slog.LevelError
+	case e.status >= 400:
+		level = slog.LevelWarn
+	}
+
+	ctx := e.r.Context()
+	if !handler.Enabled(ctx, level) {
+		return
+	}
+
+	record := slog.NewRecord(e.start, level, "http request", 0)
+	record.AddAttrs(
+		slog.Int("status", e.status),
+		slog.Duration("latency", e.latency),
+		slog.Int64("bytes", e.bytesOut),
+		slog.String("method", e.r.Method),
+		slog.String("path", e.r.URL.Path),
+		slog.String("ip", e.ip),
+		slog.String("user_agent", e.r.UserAgent()),
+	)
+
+	if e.err != nil {
+		record.AddAttrs(slog.String("error", e.err.Error()))
+	}
+
+	handler.Handle(ctx, record)
+}
+
+// logFormat is a compiled log format. Each part writes either a literal
+// or the value of a placeholder.
+type logFormat []func(buf *bytes.Buffer, e *logEntry)
+
+// compileLogFormat parses the format once, so requests only have to
+// write the values of the placeholders.
+func compileLogFormat(format, timeFormat string, escape func(string) string) logFormat {
+	var parts logFormat
+	for format != "" {
+		start := strings.Index(format, "${")
+		end := -1
+		if start >= 0 {
+			end = strings.Index(format[start:], "}")
+		}
+
+		if start < 0 || end < 0 {
+			parts = append(parts, literalLogPart(format))
+			break
+		}
+
+		end += start
+		if start > 0 {
+			parts = append(parts, literalLogPart(format[:start]))
+		}
+
+		placeholder := format[start : end+1]
+		part := logPart(placeholder[2:len(placeholder)-1], timeFormat)
+		if part == nil {
+			part = literalLogPart(placeholder)
+		} else if escape != nil {
+			part = escapedLogPart(part, escape)
+		}
+
+		parts = append(parts, part)
+		format = format[end+1:]
+	}
+	return parts
+}
+
+func (f logFormat) write(buf *bytes.Buffer, e *logEntry) {
+	for _, part := range f {
+		part(buf, e)
+	}
+}
+
+func literalLogPart(s string) func(*bytes.Buffer, *logEntry) {
+	return func(buf *bytes.Buffer, _ *logEntry) {
+		buf.WriteString(s)
+	}
+}
+
+func escapedLogPart(part func(*bytes.Buffer, *logEntry), escape func(string) string) func(*bytes.Buffer, *logEntry) {
+	return func(buf *bytes.Buffer, e *logEntry) {
+		value := buffers.Get().(*bytes.Buffer)
+		defer buffers.Put(value)
+		value.Reset()
+
+		part(value, e)
+		buf.WriteString(escape(value.String()))
+	}
+}
+
+// LogEscapeCLF escapes values for the NCSA log formats. Quotes, backslashes
+// and non-printable characters are escaped and empty values become "-".
+func LogEscapeCLF(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// LogEscapeJSON escapes values to be placed inside a JSON string.
+func LogEscapeJSON(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data[1 : len(data)-1])
+}
+
+// logPart returns the function that writes the value of the placeholder,
+// or nil if the placeholder is unknown.
+func logPart(name, timeFormat string) func(*bytes.Buffer, *logEntry) {
+	if header, ok := strings.CutPrefix(name, "header:"); ok {
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.WriteString(e.r.Header.Get(header))
+		}
+	}
+
+	if cookie, ok := strings.CutPrefix(name, "cookie:"); ok {
+		return func(buf *bytes.Buffer, e *logEntry) {
+			if c, err := e.r.Cookie(cookie); err == nil {
+				buf.WriteString(c.Value)
+			}
+		}
+	}
+
+	switch name {
+	case "time":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.Write(e.start.AppendFormat(buf.AvailableBuffer(), timeFormat))
+		}
+	case "status":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(e.status), 10))
+		}
+	case "latency":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.WriteString(e.latency.String())
+		}
+	case "latency_ms":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			ms := float64(e.latency) / float64(time.Millisecond)
+			buf.Write(strconv.AppendFloat(buf.AvailableBuffer(), ms, 'f', 3, 64))
+		}
+	case "ip":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.WriteString(e.ip)
+		}
+	case "method":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.WriteString(e.r.Method)
+		}
+	case "path":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.WriteString(e.r.URL.Path)
+		}
+	case "query":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.WriteString(e.r.URL.RawQuery)
+		}
+	case "uri":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.WriteString(e.r.RequestURI)
+		}
+	case "proto":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.WriteString(e.r.Proto)
+		}
+	case "host":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.WriteString(e.r.Host)
+		}
+	case "referer":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.WriteString(e.r.Referer())
+		}
+	case "user_agent":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.WriteString(e.r.UserAgent())
+		}
+	case "bytes_in":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.Write(strconv.AppendInt(buf.AvailableBuffer(), e.bytesIn, 10))
+		}
+	case "bytes_out":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			buf.Write(strconv.AppendInt(buf.AvailableBuffer(), e.bytesOut, 10))
+		}
+	case "error":
+		return func(buf *bytes.Buffer, e *logEntry) {
+			if e.err != nil {
+				buf.WriteString(e.err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// withBody returns a shallow copy of the request with the given body.
+func withBody(r *http.Request, body io.ReadCloser) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.Body = body
+	return r2
+}
+
+// countingReader counts the bytes read from the request body.
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
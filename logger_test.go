@@ -2,9 +2,15 @@ package httpx_test
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bluescreen10/httpx"
 )
@@ -28,3 +34,165 @@ func TestLogger(t *testing.T) {
 		t.Fatalf("invalid log expected '%s' got '%s'", expected, got)
 	}
 }
+
+func TestLoggerError(t *testing.T) {
+	h := httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return httpx.NewHTTPError(http.StatusForbidden, "forbidden")
+	})
+
+	output := &bytes.Buffer{}
+	logger := httpx.LoggerWithConfig(httpx.LoggerConfig{Format: "${status} ${error}", Output: output})
+
+	r := httptest.NewRequest("GET", "/endpoint", &bytes.Buffer{})
+	w := httptest.NewRecorder()
+
+	logger(h).ServeHTTP(w, r)
+
+	got := output.String()
+	expected := "403 forbidden"
+	if got != expected {
+		t.Fatalf("invalid log expected '%s' got '%s'", expected, got)
+	}
+}
+
+func TestLoggerSlog(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("boom"))
+	})
+
+	output := &bytes.Buffer{}
+	logger := httpx.LoggerWithConfig(httpx.LoggerConfig{
+		Logger: slog.New(slog.NewJSONHandler(output, nil)),
+	})
+
+	r := httptest.NewRequest("GET", "/endpoint", &bytes.Buffer{})
+	r.Header.Set("User-Agent", "test")
+	w := httptest.NewRecorder()
+
+	logger(h).ServeHTTP(w, r)
+
+	var entry map[string]any
+	if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry["level"] != "ERROR" || entry["status"] != 500.0 || entry["bytes"] != 4.0 ||
+		entry["method"] != "GET" || entry["path"] != "/endpoint" || entry["user_agent"] != "test" {
+		t.Fatalf("unexpected log entry '%v'", entry)
+	}
+}
+
+func TestLoggerPlaceholders(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.Write([]byte("hello"))
+	})
+
+	output := &bytes.Buffer{}
+	logger := httpx.LoggerWithConfig(httpx.LoggerConfig{
+		Format:     "${time} ${uri} ${query} ${proto} ${host} ${referer} ${user_agent} ${bytes_in} ${bytes_out} ${header:X-Request-Id} ${cookie:sid} ${unknown}",
+		TimeFormat: "2006",
+		Output:     output,
+	})
+
+	r := httptest.NewRequest("POST", "/endpoint?a=1", strings.NewReader("body"))
+	r.Header.Set("Referer", "http://example.com/")
+	r.Header.Set("User-Agent", "test")
+	r.Header.Set("X-Request-Id", "abc")
+	r.AddCookie(&http.Cookie{Name: "sid", Value: "123"})
+	w := httptest.NewRecorder()
+
+	logger(h).ServeHTTP(w, r)
+
+	expected := fmt.Sprintf("%d /endpoint?a=1 a=1 HTTP/1.1 example.com http://example.com/ test 4 5 abc 123 ${unknown}", time.Now().Year())
+	if got := output.String(); got != expected {
+		t.Fatalf("invalid log expected '%s' got '%s'", expected, got)
+	}
+}
+
+func TestLoggerCombined(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+
+	output := &bytes.Buffer{}
+	cfg := httpx.CombinedLoggerConfig
+	cfg.Output = output
+	logger := httpx.LoggerWithConfig(cfg)
+
+	r := httptest.NewRequest("GET", "/endpoint?a=1", &bytes.Buffer{})
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("User-Agent", `agent "quoted"`)
+	w := httptest.NewRecorder()
+
+	logger(h).ServeHTTP(w, r)
+
+	got := output.String()
+	expected := `"GET /endpoint?a=1 HTTP/1.1" 200 5 "-" "agent \"quoted\""` + "\n"
+	if !strings.HasPrefix(got, "10.0.0.1 - - [") || !strings.HasSuffix(got, expected) {
+		t.Fatalf("invalid log got '%s'", got)
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	h := httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return httpx.NewHTTPError(http.StatusBadRequest, `bad "input"`)
+	})
+
+	output := &bytes.Buffer{}
+	cfg := httpx.JSONLoggerConfig
+	cfg.Output = output
+	logger := httpx.LoggerWithConfig(cfg)
+
+	r := httptest.NewRequest("GET", "/endpoint", &bytes.Buffer{})
+	w := httptest.NewRecorder()
+
+	logger(h).ServeHTTP(w, r)
+
+	var entry map[string]any
+	if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid json '%s': %s", output.String(), err)
+	}
+
+	if entry["status"] != 400.0 || entry["error"] != `bad "input"` {
+		t.Fatalf("unexpected log entry '%v'", entry)
+	}
+}
+
+func TestLoggerSkip(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	output := &bytes.Buffer{}
+	logger := httpx.LoggerWithConfig(httpx.LoggerConfig{
+		Format: "${method} ${path} ${status}\n",
+		Output: output,
+		Skip: []httpx.LogSkipper{
+			httpx.LogSkipPath("/health", "/_livereload"),
+			httpx.LogSkipMethod(http.MethodOptions),
+			httpx.LogSkipStatus(300, 399),
+			httpx.LogSample(map[int]float64{2: 0}),
+		},
+	})
+
+	for _, req := range []struct{ method, path string }{
+		{"GET", "/health/live"},
+		{"GET", "/_livereload"},
+		{"OPTIONS", "/users"},
+		{"GET", "/users"},
+		{"GET", "/missing"},
+	} {
+		r := httptest.NewRequest(req.method, req.path, &bytes.Buffer{})
+		w := httptest.NewRecorder()
+		logger(h).ServeHTTP(w, r)
+	}
+
+	expected := "GET /missing 404\n"
+	if got := output.String(); got != expected {
+		t.Fatalf("invalid log expected '%s' got '%s'", expected, got)
+	}
+}
@@ -4,3 +4,22 @@ import "net/http"
 
 // Middleware defines the interface for HTTP middleware compatible with ServeMux.
 type Middleware func(http.Handler) http.Handler
+
+// contextKey is the type of the keys used by the middlewares of this
+// package to store values in the request context.
+type contextKey int
+
+const (
+	errorSlotKey contextKey = iota
+	errorHandlerKey
+	clientIPKey
+)
+
+// chain wraps the handler with the middlewares, so that the first
+// middleware is the outermost one.
+func chain(handler http.Handler, middlewares []Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
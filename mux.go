@@ -1,8 +1,15 @@
 package httpx
 
 import (
+	"context"
+	"fmt"
+	"html/template"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
 	"strings"
+	"sync"
 )
 
 // ServeMux is a wrapper around http.ServeMux that adds support for
@@ -20,55 +27,179 @@ import (
 //
 //	// Register handlers on the group
 //	api.HandleFunc("/users", usersHandler)
-//	api.HandleFunc("/posts", postsHandler)
+//	api.HandleFunc("GET /users/{id}", userHandler).Name("user")
+//
+//	// Build links from route names
+//	link, _ := mux.URL("user", "id", 42) // "/api/users/42"
 //
 //	http.ListenAndServe(":8080", mux)
+//
+// Each call to Use wraps the middlewares added before, so the last call runs
+// first. A request to a route inside a group runs the middlewares of the
+// parent mux first, then the ones given to Group, then the ones added to the
+// group with Use, and finally the ones attached to the route itself. Middlewares added with Use on a group only
+// apply to the routes of that group (and its own sub-groups).
+//
+// The responses for unmatched requests can be customized with NotFound and
+// MethodNotAllowed. Groups inherit them from their parent unless they set
+// their own:
+//
+//	mux.NotFound(http.HandlerFunc(notFoundPage))
+//	api.NotFound(http.HandlerFunc(notFoundJSON))
+//
+// Errors returned by HandlerFunc handlers are written by the error handler
+// set with SetErrorHandler, which is also inherited by groups.
 type ServeMux struct {
 	*http.ServeMux
 	handler http.Handler
+
+	// prefix is the full path prefix of the mux, including the
+	// prefixes of all its parents. It is empty for the root mux.
+	prefix string
+	parent *ServeMux
+
+	// groupMiddlewares are the middlewares given to Group when the
+	// mux was created, middlewares are the ones added with Use.
+	groupMiddlewares []Middleware
+	middlewares      []Middleware
+
+	notFound         http.Handler
+	methodNotAllowed http.Handler
+	errorHandler     ErrorHandler
+
+	routes *routeTable
+}
+
+// Route is a pattern registered on a ServeMux. It can be given a name
+// so URLs can be generated from it with ServeMux.URL.
+type Route struct {
+	// Pattern is the pattern as it was registered on the mux.
+	Pattern string
+
+	// Method and Host are the method and host of the pattern, empty
+	// when the pattern matches any of them.
+	Method string
+	Host   string
+
+	// Path is the full path of the route, including the prefixes of
+	// the groups it was registered in.
+	Path string
+
+	// Mount reports whether the route is the mount point of a group
+	// ("/api/"), which strips the prefix and passes the request to the
+	// routes of the group.
+	Mount bool
+
+	name        string
+	mux         *ServeMux
+	middlewares []Middleware
+	routes      *routeTable
+}
+
+// routeTable keeps track of the registered and named routes, and the
+// fingerprinted static assets. It is shared between a mux and all its
+// groups.
+type routeTable struct {
+	mu     sync.RWMutex
+	routes []*Route
+	names  map[string]*Route
+	assets map[string]string
 }
 
 // NewServeMux creates a new ServeMux instance.
 func NewServeMux() *ServeMux {
 	mux := new(ServeMux)
 	mux.ServeMux = http.NewServeMux()
-	mux.handler = mux.ServeMux
+	mux.handler = http.HandlerFunc(mux.dispatch)
+	mux.routes = &routeTable{
+		names:  make(map[string]*Route),
+		assets: make(map[string]string),
+	}
 	return mux
 }
 
+// Handle registers the handler for the given pattern. See http.ServeMux
+// for the pattern syntax. The returned Route can be used to name the route.
+func (mux *ServeMux) Handle(pattern string, handler http.Handler) *Route {
+	mux.ServeMux.Handle(pattern, handler)
+	return mux.newRoute(pattern)
+}
+
+// HandleFunc registers the handler function for the given pattern. See
+// http.ServeMux for the pattern syntax. The returned Route can be used to
+// name the route.
+func (mux *ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	mux.ServeMux.HandleFunc(pattern, handler)
+	return mux.newRoute(pattern)
+}
+
 // Group creates a sub-router with the given prefix and optional middlewares.
 // The returned sub-router can register its own handlers, which will inherit
-// the parent middlewares automatically.
+// the parent middlewares automatically. Groups can be nested, in which case
+// prefixes and middlewares are composed.
 func (mux *ServeMux) Group(prefix string, middlewares ...Middleware) *ServeMux {
 	prefix = strings.TrimSuffix(prefix, "/")
 	subMux := NewServeMux()
+	subMux.prefix = mux.prefix + prefix
+	subMux.parent = mux
+	subMux.groupMiddlewares = middlewares
+	subMux.routes = mux.routes
 
-	var wrapped http.Handler = subMux
+	wrapped := chain(subMux, middlewares)
+	mux.ServeMux.Handle(prefix+"/", http.StripPrefix(prefix, wrapped))
 
-	for i := len(middlewares) - 1; i >= 0; i-- {
-		wrapped = middlewares[i](wrapped)
-	}
-
-	mux.Handle(prefix+"/", http.StripPrefix(prefix, wrapped))
+	mux.addRoute(&Route{
+		Pattern:     prefix + "/",
+		Path:        subMux.prefix + "/",
+		Mount:       true,
+		mux:         mux,
+		middlewares: middlewares,
+		routes:      mux.routes,
+	})
 	return subMux
 }
 
 // Static mounts a file server with the give prefix and optional middlewares.
-// The dir specifies the root of the file server.
-func (mux *ServeMux) Static(prefix, dir string, middlewares ...Middleware) {
-	handler := http.FileServer(http.Dir(dir))
+// The dir specifies the root of the file server. Directory listings are
+// enabled, use StaticFS for more options.
+func (mux *ServeMux) Static(prefix, dir string, middlewares ...Middleware) *Route {
+	return mux.StaticFS(prefix, os.DirFS(dir), StaticConfig{Browse: true}, middlewares...)
+}
 
-	for i := len(middlewares) - 1; i >= 0; i-- {
-		handler = middlewares[i](handler)
-	}
+// Use adds middlewares to the ServeMux. These middlewares are applied to all
+// routes registered on this mux, including the ones registered before calling
+// Use. Each call wraps the middlewares added by the previous calls, so they
+// run before them. The middlewares given in a single call run in order.
+func (mux *ServeMux) Use(middlewares ...Middleware) {
+	mux.middlewares = append(slices.Clone(middlewares), mux.middlewares...)
+	mux.handler = chain(mux.handler, middlewares)
+}
+
+// NotFound sets the handler used when no route of the mux matches the
+// request. It replaces the plain text 404 response of http.ServeMux and
+// applies to the groups of the mux that don't set their own.
+func (mux *ServeMux) NotFound(handler http.Handler) {
+	mux.notFound = handler
+}
 
-	mux.Handle(prefix, http.StripPrefix(prefix, handler))
+// MethodNotAllowed sets the handler used when a route of the mux matches the
+// request path but not its method. The Allow header is already set when the
+// handler is called. It replaces the plain text 405 response of http.ServeMux
+// and applies to the groups of the mux that don't set their own.
+func (mux *ServeMux) MethodNotAllowed(handler http.Handler) {
+	mux.methodNotAllowed = handler
 }
 
-// Use adds a global middleware to the ServeMux. These middlewares are applied
-// to all routes registered on this mux.
-func (mux *ServeMux) Use(middleware Middleware) {
-	mux.handler = middleware(mux.handler)
+// Middlewares returns the effective middleware chain of the routes registered
+// on this mux, in the order they run. It includes the middlewares inherited
+// from parent muxes.
+func (mux *ServeMux) Middlewares() []Middleware {
+	var middlewares []Middleware
+	if mux.parent != nil {
+		middlewares = mux.parent.Middlewares()
+	}
+	middlewares = append(middlewares, mux.groupMiddlewares...)
+	return append(middlewares, mux.middlewares...)
 }
 
 // ServeHTTP implements http.Handler and applies global middlewares
@@ -76,3 +207,248 @@ func (mux *ServeMux) Use(middleware Middleware) {
 func (mux *ServeMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	mux.handler.ServeHTTP(w, r)
 }
+
+// SetErrorHandler sets the handler used to write the response for errors
+// returned by HandlerFunc handlers registered on the mux and its groups,
+// unless a group sets its own. By default DefaultErrorHandler is used.
+func (mux *ServeMux) SetErrorHandler(handler ErrorHandler) {
+	mux.errorHandler = handler
+}
+
+// dispatch sends the request to the underlying http.ServeMux. When no route
+// matches, the 404 and 405 responses are replaced with the NotFound and
+// MethodNotAllowed handlers.
+func (mux *ServeMux) dispatch(w http.ResponseWriter, r *http.Request) {
+	for m := mux; m != nil; m = m.parent {
+		if m.errorHandler != nil {
+			r = r.WithContext(context.WithValue(r.Context(), errorHandlerKey, m.errorHandler))
+			break
+		}
+	}
+
+	notFound, methodNotAllowed := mux.fallbackHandlers()
+	if notFound == nil && methodNotAllowed == nil {
+		mux.ServeMux.ServeHTTP(w, r)
+		return
+	}
+
+	if _, pattern := mux.ServeMux.Handler(r); pattern != "" {
+		mux.ServeMux.ServeHTTP(w, r)
+		return
+	}
+
+	mux.ServeMux.ServeHTTP(&notFoundWriter{
+		ResponseWriter:   w,
+		r:                r,
+		notFound:         notFound,
+		methodNotAllowed: methodNotAllowed,
+	}, r)
+}
+
+// fallbackHandlers returns the NotFound and MethodNotAllowed handlers of the
+// mux, falling back to the ones of its parents.
+func (mux *ServeMux) fallbackHandlers() (notFound, methodNotAllowed http.Handler) {
+	for m := mux; m != nil; m = m.parent {
+		if notFound == nil {
+			notFound = m.notFound
+		}
+		if methodNotAllowed == nil {
+			methodNotAllowed = m.methodNotAllowed
+		}
+	}
+	return notFound, methodNotAllowed
+}
+
+// notFoundWriter intercepts the 404 and 405 responses written by
+// http.ServeMux and calls the custom handlers instead.
+type notFoundWriter struct {
+	http.ResponseWriter
+	r                *http.Request
+	notFound         http.Handler
+	methodNotAllowed http.Handler
+	intercepted      bool
+}
+
+func (w *notFoundWriter) WriteHeader(status int) {
+	var handler http.Handler
+	switch status {
+	case http.StatusNotFound:
+		handler = w.notFound
+	case http.StatusMethodNotAllowed:
+		handler = w.methodNotAllowed
+	}
+
+	if handler == nil {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	// http.Error sets these before writing the status
+	w.intercepted = true
+	w.Header().Del("Content-Type")
+	w.Header().Del("X-Content-Type-Options")
+	handler.ServeHTTP(w.ResponseWriter, w.r)
+}
+
+func (w *notFoundWriter) Write(data []byte) (int, error) {
+	if w.intercepted {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// URL builds the path of the route registered with the given name. The
+// params are key/value pairs used to fill the wildcards of the pattern,
+// e.g. mux.URL("user", "id", 42) for "/users/{id}". Params that don't
+// match a wildcard are added to the query string.
+func (mux *ServeMux) URL(name string, params ...any) (string, error) {
+	mux.routes.mu.RLock()
+	route, ok := mux.routes.names[name]
+	mux.routes.mu.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("route %q not found", name)
+	}
+
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("route %q: odd number of params", name)
+	}
+
+	values := make(map[string]string, len(params)/2)
+	keys := make([]string, 0, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return "", fmt.Errorf("route %q: param name must be a string, got %T", name, params[i])
+		}
+		values[key] = fmt.Sprint(params[i+1])
+		keys = append(keys, key)
+	}
+
+	segments := strings.Split(route.Path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+
+		wildcard := segment[1 : len(segment)-1]
+		if wildcard == "$" {
+			segments[i] = ""
+			continue
+		}
+
+		key, isRest := strings.CutSuffix(wildcard, "...")
+		value, ok := values[key]
+		if !ok {
+			return "", fmt.Errorf("route %q: missing param %q", name, key)
+		}
+		delete(values, key)
+
+		if isRest {
+			parts := strings.Split(value, "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+		} else {
+			segments[i] = url.PathEscape(value)
+		}
+	}
+
+	path := strings.Join(segments, "/")
+	if len(values) == 0 {
+		return path, nil
+	}
+
+	query := url.Values{}
+	for _, key := range keys {
+		if value, ok := values[key]; ok {
+			query.Set(key, value)
+		}
+	}
+	return path + "?" + query.Encode(), nil
+}
+
+// FuncMap returns the template functions provided by the ServeMux, so
+// they can be registered on a Renderer. It includes "url" which builds
+// the path of a named route, and "asset" which returns the fingerprinted
+// path of a static file:
+//
+//	renderer.Funcs(mux.FuncMap())
+//
+//	<a href="{{ url "user" "id" .User.ID }}">profile</a>
+//	<script src="{{ asset "/static/app.js" }}"></script>
+func (mux *ServeMux) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"url":   mux.URL,
+		"asset": mux.Asset,
+	}
+}
+
+// Middlewares returns the effective middleware chain of the route, in the
+// order they run.
+func (rt *Route) Middlewares() []Middleware {
+	return append(rt.mux.Middlewares(), rt.middlewares...)
+}
+
+// GetName returns the name of the route, or "" if it has no name.
+func (rt *Route) GetName() string {
+	rt.routes.mu.RLock()
+	defer rt.routes.mu.RUnlock()
+	return rt.name
+}
+
+// Name sets the name of the route, so its URL can be generated with
+// ServeMux.URL. It panics if the name is already taken by another route.
+func (rt *Route) Name(name string) *Route {
+	rt.routes.mu.Lock()
+	defer rt.routes.mu.Unlock()
+
+	if other, ok := rt.routes.names[name]; ok && other != rt {
+		panic(fmt.Sprintf("httpx: route name %q already registered for %q", name, other.Pattern))
+	}
+
+	if rt.name != "" {
+		delete(rt.routes.names, rt.name)
+	}
+
+	rt.name = name
+	rt.routes.names[name] = rt
+	return rt
+}
+
+func (mux *ServeMux) newRoute(pattern string) *Route {
+	method, host, path := parsePattern(pattern)
+	route := &Route{
+		Pattern: pattern,
+		Method:  method,
+		Host:    host,
+		Path:    mux.prefix + path,
+		mux:     mux,
+		routes:  mux.routes,
+	}
+	mux.addRoute(route)
+	return route
+}
+
+func (mux *ServeMux) addRoute(route *Route) {
+	mux.routes.mu.Lock()
+	mux.routes.routes = append(mux.routes.routes, route)
+	mux.routes.mu.Unlock()
+}
+
+// parsePattern splits a http.ServeMux pattern ("[METHOD ][HOST]/[PATH]")
+// into its components.
+func parsePattern(pattern string) (method, host, path string) {
+	rest := strings.TrimSpace(pattern)
+	if i := strings.IndexAny(rest, " \t"); i >= 0 {
+		method, rest = rest[:i], strings.TrimLeft(rest[i+1:], " \t")
+	}
+
+	if i := strings.Index(rest, "/"); i >= 0 {
+		host, path = rest[:i], rest[i:]
+	} else {
+		host = rest
+	}
+	return method, host, path
+}
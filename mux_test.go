@@ -2,9 +2,11 @@ package httpx_test
 
 import (
 	"bytes"
+	"html/template"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/bluescreen10/httpx"
@@ -115,3 +117,216 @@ func TestStatic(t *testing.T) {
 		t.Fatal("expected body to have contenst but got ''")
 	}
 }
+
+func TestURL(t *testing.T) {
+	mux := httpx.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {}).Name("user")
+	mux.HandleFunc("/files/{path...}", func(w http.ResponseWriter, r *http.Request) {}).Name("files")
+
+	api := mux.Group("/api")
+	api.HandleFunc("GET /posts/{id}/{$}", func(w http.ResponseWriter, r *http.Request) {}).Name("api.post")
+
+	tests := []struct {
+		name     string
+		params   []any
+		expected string
+	}{
+		{"user", []any{"id", 42}, "/users/42"},
+		{"user", []any{"id", "a b", "tab", "info"}, "/users/a%20b?tab=info"},
+		{"files", []any{"path", "css/app.css"}, "/files/css/app.css"},
+		{"api.post", []any{"id", 7}, "/api/posts/7/"},
+	}
+
+	for _, tt := range tests {
+		got, err := mux.URL(tt.name, tt.params...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != tt.expected {
+			t.Fatalf("expected url '%s' got '%s'", tt.expected, got)
+		}
+	}
+
+	if _, err := mux.URL("user"); err == nil {
+		t.Fatal("expected error for missing param")
+	}
+
+	if _, err := mux.URL("unknown"); err == nil {
+		t.Fatal("expected error for unknown route")
+	}
+}
+
+func TestURLTemplateFunc(t *testing.T) {
+	mux := httpx.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {}).Name("user")
+
+	tmpl := template.Must(template.New("").Funcs(mux.FuncMap()).Parse(`{{ url "user" "id" .ID }}`))
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, map[string]any{"ID": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := buf.String(); got != "/users/1" {
+		t.Fatalf("expected '/users/1' got '%s'", got)
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	mw := func(name string) httpx.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	mux := httpx.NewServeMux()
+	mux.Use(mw("global1"))
+	api := mux.Group("/api", mw("api"))
+	api.Use(mw("api-use"))
+	v1 := api.Group("/v1", mw("v1"))
+	v1.Use(mw("v1-use"))
+	route := v1.HandleFunc("/test", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})
+	api.HandleFunc("/other", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})
+	mux.Use(mw("global2"))
+
+	r := httptest.NewRequest("GET", "/api/v1/test", &bytes.Buffer{})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	expected := "global2,global1,api,api-use,v1,v1-use,handler"
+	if got := strings.Join(calls, ","); got != expected {
+		t.Fatalf("expected calls '%s' got '%s'", expected, got)
+	}
+
+	if n := len(route.Middlewares()); n != 6 {
+		t.Fatalf("expected '6' middlewares got '%d'", n)
+	}
+
+	calls = nil
+	r = httptest.NewRequest("GET", "/api/other", &bytes.Buffer{})
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	expected = "global2,global1,api,api-use,handler"
+	if got := strings.Join(calls, ","); got != expected {
+		t.Fatalf("expected calls '%s' got '%s'", expected, got)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	mux := httpx.NewServeMux()
+	mux.NotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("page not found"))
+	}))
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {})
+
+	api := mux.Group("/api")
+	api.NotFound(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not found"}`))
+	}))
+	api.HandleFunc("GET /users", func(w http.ResponseWriter, r *http.Request) {})
+
+	admin := mux.Group("/admin")
+	admin.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/missing", "page not found"},
+		{"/api/missing", `{"error":"not found"}`},
+		{"/admin/missing", "404 page not found\n"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.path, &bytes.Buffer{})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected status '404' got '%d'", w.Code)
+		}
+
+		if body := w.Body.String(); body != tt.expected {
+			t.Fatalf("expected body '%s' got '%s'", tt.expected, body)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	mux := httpx.NewServeMux()
+	mux.MethodNotAllowed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("custom"))
+	}))
+
+	api := mux.Group("/api")
+	api.HandleFunc("GET /users", func(w http.ResponseWriter, r *http.Request) {})
+	api.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {})
+
+	r := httptest.NewRequest("DELETE", "/api/users", &bytes.Buffer{})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status '405' got '%d'", w.Code)
+	}
+
+	if body := w.Body.String(); body != "custom" {
+		t.Fatalf("expected body 'custom' got '%s'", body)
+	}
+
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, POST" {
+		t.Fatalf("expected allow header 'GET, HEAD, POST' got '%s'", allow)
+	}
+}
+
+func TestUseOrder(t *testing.T) {
+	var calls []string
+	built := 0
+	mw := func(name string) httpx.Middleware {
+		return func(next http.Handler) http.Handler {
+			built++
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	mux := httpx.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})
+
+	// each call wraps the previous ones
+	mux.Use(mw("first"))
+	mux.Use(mw("second"), mw("third"))
+
+	r := httptest.NewRequest("GET", "/", &bytes.Buffer{})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	expected := "second,third,first,handler"
+	if got := strings.Join(calls, ","); got != expected {
+		t.Fatalf("expected calls '%s' got '%s'", expected, got)
+	}
+
+	// the middlewares added before aren't built again
+	if built != 3 {
+		t.Fatalf("expected '3' middlewares built got '%d'", built)
+	}
+}
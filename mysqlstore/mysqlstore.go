@@ -6,6 +6,7 @@
 package mysqlstore
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -24,9 +25,13 @@ func New(db *sql.DB) (*MySQLStore, error) {
 // the data, a boolean indicating whether the token was found and
 // not expired, and an error.
 func (s *MySQLStore) Get(token string) ([]byte, bool, error) {
+	return s.GetCtx(context.Background(), token)
+}
 
+// GetCtx is like Get, using the context for the query.
+func (s *MySQLStore) GetCtx(ctx context.Context, token string) ([]byte, bool, error) {
 	stmt := "SELECT data FROM sessions WHERE token = ? AND UTC_TIMESTAMP(6) < expires_at"
-	row := s.db.QueryRow(stmt, token)
+	row := s.db.QueryRowContext(ctx, stmt, token)
 
 	var data []byte
 	err := row.Scan(&data)
@@ -44,15 +49,25 @@ func (s *MySQLStore) Get(token string) ([]byte, bool, error) {
 // a record with the same token already exists, it is overwritten. The
 // expiresAt parameter specifies when the record should be considered expired.
 func (s *MySQLStore) Set(token string, data []byte, expiresAt time.Time) error {
+	return s.SetCtx(context.Background(), token, data, expiresAt)
+}
+
+// SetCtx is like Set, using the context for the query.
+func (s *MySQLStore) SetCtx(ctx context.Context, token string, data []byte, expiresAt time.Time) error {
 	stmt := "INSERT INTO sessions(token, data, expires_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE data = VALUES(data), expires_at = VALUES(expires_at)"
-	_, err := s.db.Exec(stmt, token, data, expiresAt.UTC())
+	_, err := s.db.ExecContext(ctx, stmt, token, data, expiresAt.UTC())
 	return err
 }
 
 // Delete removes the data associated with the given token.
 func (s *MySQLStore) Delete(token string) error {
+	return s.DeleteCtx(context.Background(), token)
+}
+
+// DeleteCtx is like Delete, using the context for the query.
+func (s *MySQLStore) DeleteCtx(ctx context.Context, token string) error {
 	stmt := "DELETE FROM sessions WHERE token = ?"
-	_, err := s.db.Exec(stmt, token)
+	_, err := s.db.ExecContext(ctx, stmt, token)
 	return err
 }
 
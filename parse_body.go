@@ -46,6 +46,12 @@ import (
 //   - conversion to the target type fails
 //   - request body cannot be read or parsed
 //
+// Errors caused by the request are returned as a *BodyError, which lists the
+// offending fields and carries the status code for the response (415 for an
+// unsupported content type, 422 for missing required fields and 400 for any
+// other binding failure). When returned from a HandlerFunc together with
+// ProblemErrorHandler, they are written as a problem document.
+//
 // Usage:
 //
 //	type CreateUserRequest struct {
@@ -72,8 +78,53 @@ func ParseBody(r *http.Request, dst any) error {
 	case "application/xml":
 		return parseBodyXML(r, dst)
 	default:
-		return fmt.Errorf("content type not supported")
+		return &BodyError{Status: http.StatusUnsupportedMediaType, Err: errors.New("content type not supported")}
+	}
+}
+
+// FieldError describes why a single field of the request body could not be
+// bound.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// BodyError is returned by ParseBody when the request body is not valid for
+// the destination. Fields lists the offending fields, if known.
+type BodyError struct {
+	// Status is the HTTP status code that should be sent to the client.
+	Status int
+
+	// Fields are the fields that failed to bind or validate.
+	Fields []FieldError
+
+	// Err is the underlying error, if any.
+	Err error
+}
+
+// Error returns the field errors joined by "; ", or the underlying error.
+func (e *BodyError) Error() string {
+	if len(e.Fields) == 0 {
+		if e.Err != nil {
+			return e.Err.Error()
+		}
+		return http.StatusText(e.Status)
 	}
+
+	msgs := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		msgs[i] = field.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the underlying error.
+func (e *BodyError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BodyError) statusCode() int {
+	return e.Status
 }
 
 // parseBodyForm parses form data from the HTTP request into a struct.
@@ -86,7 +137,7 @@ func ParseBody(r *http.Request, dst any) error {
 // appropriate Go types using bindFieldValue.
 func parseBodyForm(r *http.Request, dst any) error {
 	if err := r.ParseForm(); err != nil {
-		return fmt.Errorf("failed to parse form: %w", err)
+		return &BodyError{Status: http.StatusBadRequest, Err: fmt.Errorf("failed to parse form: %w", err)}
 	}
 
 	rv := reflect.ValueOf(dst)
@@ -100,6 +151,7 @@ func parseBodyForm(r *http.Request, dst any) error {
 	}
 
 	rt := rv.Type()
+	bodyErr := &BodyError{Status: http.StatusUnprocessableEntity}
 
 	for i := 0; i < rv.NumField(); i++ {
 		field := rv.Field(i)
@@ -128,7 +180,11 @@ func parseBodyForm(r *http.Request, dst any) error {
 		formValues := r.Form[fieldName]
 
 		if required && len(formValues) == 0 {
-			return fmt.Errorf("required field '%s' is missing", fieldName)
+			bodyErr.Fields = append(bodyErr.Fields, FieldError{
+				Field:   fieldName,
+				Message: fmt.Sprintf("required field '%s' is missing", fieldName),
+			})
+			continue
 		}
 
 		if len(formValues) == 0 {
@@ -136,10 +192,20 @@ func parseBodyForm(r *http.Request, dst any) error {
 		}
 
 		if err := bindFieldValue(field, formValues); err != nil {
-			return fmt.Errorf("failed to bind field '%s': %w", fieldName, err)
+			// malformed values are a bad request, missing ones are
+			// only a validation failure
+			bodyErr.Status = http.StatusBadRequest
+			bodyErr.Fields = append(bodyErr.Fields, FieldError{
+				Field:   fieldName,
+				Message: fmt.Sprintf("failed to bind field '%s': %s", fieldName, err),
+			})
 		}
 	}
 
+	if len(bodyErr.Fields) > 0 {
+		return bodyErr
+	}
+
 	return nil
 }
 
@@ -224,7 +290,22 @@ func parseBodyJSON(r *http.Request, dst any) error {
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(body, dst)
+
+	err = json.Unmarshal(body, dst)
+
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr):
+		return &BodyError{
+			Status: http.StatusBadRequest,
+			Fields: []FieldError{{Field: typeErr.Field, Message: err.Error()}},
+			Err:    err,
+		}
+	case errors.As(err, &syntaxErr):
+		return &BodyError{Status: http.StatusBadRequest, Err: err}
+	}
+	return err
 }
 
 // parseBodyXML parses XML data from the HTTP request body into a struct.
@@ -240,5 +321,12 @@ func parseBodyXML(r *http.Request, dst any) error {
 	if err != nil {
 		return err
 	}
-	return xml.Unmarshal(body, dst)
+
+	err = xml.Unmarshal(body, dst)
+
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &BodyError{Status: http.StatusBadRequest, Err: err}
+	}
+	return err
 }
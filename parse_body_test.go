@@ -2,6 +2,8 @@ package httpx_test
 
 import (
 	"bytes"
+	"errors"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
@@ -75,3 +77,26 @@ func TestSimpleXML(t *testing.T) {
 		t.Fatal("error parsing xml")
 	}
 }
+
+func TestFormBodyError(t *testing.T) {
+	body := bytes.NewReader([]byte("age=abc"))
+	r := httptest.NewRequest("POST", "/", body)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	type user struct {
+		Name string `form:"name,required"`
+		Age  int    `form:"age"`
+	}
+
+	u := user{}
+
+	var bodyErr *httpx.BodyError
+	err := httpx.ParseBody(r, &u)
+	if !errors.As(err, &bodyErr) {
+		t.Fatalf("expected BodyError got '%v'", err)
+	}
+
+	if bodyErr.Status != http.StatusBadRequest || len(bodyErr.Fields) != 2 {
+		t.Fatalf("expected status '400' and '2' fields got '%d' and '%d'", bodyErr.Status, len(bodyErr.Fields))
+	}
+}
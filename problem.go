@@ -0,0 +1,239 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Problem is an RFC 9457 problem details object. It can be written with
+// WriteProblem or returned as an error from a HandlerFunc.
+//
+// Usage:
+//
+//	mux.SetErrorHandler(httpx.ProblemErrorHandler)
+//
+//	mux.Handle("POST /users", httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+//		var req CreateUserRequest
+//		if err := httpx.ParseBody(r, &req); err != nil {
+//			return err // written as a 400/422 problem listing the fields
+//		}
+//
+//		if exists(req.Email) {
+//			return httpx.NewProblem(http.StatusConflict, "email already registered")
+//		}
+//		...
+//	}))
+type Problem struct {
+	// Type is a URI that identifies the problem type. Defaults to
+	// "about:blank".
+	Type string
+
+	// Title is a short summary of the problem type. Defaults to the
+	// status text.
+	Title string
+
+	// Status is the HTTP status code.
+	Status int
+
+	// Detail is an explanation specific to this occurrence.
+	Detail string
+
+	// Instance is a URI that identifies this occurrence.
+	Instance string
+
+	// Extensions are additional members of the problem document.
+	Extensions map[string]any
+}
+
+// NewProblem returns a Problem with the given status and detail.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{Status: status, Detail: detail}
+}
+
+// ProblemFromError converts an error to a Problem. Problems are returned as
+// is, HTTPError and BodyError keep their status and message (BodyError lists
+// its fields in the "errors" extension) and any other error becomes a 500
+// Internal Server Error without details.
+func ProblemFromError(err error) *Problem {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		return problem
+	}
+
+	status := ErrorStatus(err)
+	problem = &Problem{Status: status}
+
+	var httpErr *HTTPError
+	var bodyErr *BodyError
+	switch {
+	case errors.As(err, &httpErr):
+		problem.Detail = httpErr.Message
+	case errors.As(err, &bodyErr):
+		problem.Detail = bodyErr.Error()
+		if len(bodyErr.Fields) > 0 {
+			problem.Extensions = map[string]any{"errors": bodyErr.Fields}
+		}
+	}
+	return problem
+}
+
+// ProblemErrorHandler is an ErrorHandler that writes errors as problem
+// documents.
+func ProblemErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	WriteProblem(w, r, ProblemFromError(err))
+}
+
+// Error returns the title and detail of the problem.
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.title()
+	}
+	return p.title() + ": " + p.Detail
+}
+
+func (p *Problem) statusCode() int {
+	return p.Status
+}
+
+func (p *Problem) title() string {
+	if p.Title != "" {
+		return p.Title
+	}
+	return http.StatusText(p.Status)
+}
+
+// MarshalJSON encodes the problem with its extensions as top level members.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	doc := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		doc[k] = v
+	}
+
+	doc["type"] = p.Type
+	if p.Type == "" {
+		doc["type"] = "about:blank"
+	}
+
+	doc["title"] = p.title()
+	if p.Status != 0 {
+		doc["status"] = p.Status
+	}
+	if p.Detail != "" {
+		doc["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		doc["instance"] = p.Instance
+	}
+	return json.Marshal(doc)
+}
+
+var problemTemplate = template.Must(template.New("problem").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Status }} {{ .Title }}</title>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+{{- if .Detail }}
+<p>{{ .Detail }}</p>
+{{- end }}
+</body>
+</html>
+`))
+
+// WriteProblem writes the problem with its status code. The format is chosen
+// from the Accept header of the request: "application/problem+json" (also
+// used for "application/json" and when there is no Accept header),
+// "text/html" or "text/plain".
+func WriteProblem(w http.ResponseWriter, r *http.Request, p *Problem) {
+	status := p.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	header := w.Header()
+	header.Add("Vary", "Accept")
+
+	switch negotiate(r.Header.Get("Accept"), "application/problem+json", "application/json", "text/html", "text/plain") {
+	case "text/html":
+		header.Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		problemTemplate.Execute(w, map[string]any{"Status": status, "Title": p.title(), "Detail": p.Detail})
+
+	case "text/plain":
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+		header.Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(status)
+		fmt.Fprintln(w, p.Error())
+
+	default:
+		data, err := json.Marshal(p)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		header.Set("Content-Type", "application/problem+json")
+		w.WriteHeader(status)
+		w.Write(data)
+	}
+}
+
+// negotiate returns the offer that best matches the Accept header, honoring
+// q-values and wildcards. When several offers have the same quality, the
+// first one wins. If the header is empty or nothing matches, the first
+// offer is returned.
+func negotiate(accept string, offers ...string) string {
+	if accept == "" {
+		return offers[0]
+	}
+
+	best, bestQ := offers[0], 0.0
+	for _, offer := range offers {
+		q := acceptQuality(accept, offer)
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the q-value the Accept header gives to the media
+// type, using the most specific matching range.
+func acceptQuality(accept, mediaType string) float64 {
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+
+		s := -1
+		switch {
+		case mediaRange == mediaType:
+			s = 2
+		case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, mediaRange[:len(mediaRange)-1]):
+			s = 1
+		case mediaRange == "*/*":
+			s = 0
+		}
+
+		if s <= specificity {
+			continue
+		}
+
+		specificity, q = s, 1
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					q = v
+				}
+			}
+		}
+	}
+	return q
+}
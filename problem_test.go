@@ -0,0 +1,99 @@
+package httpx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bluescreen10/httpx"
+)
+
+func TestWriteProblem(t *testing.T) {
+	p := httpx.NewProblem(http.StatusConflict, "email already registered")
+	p.Extensions = map[string]any{"email": "ab@c.com"}
+
+	r := httptest.NewRequest("GET", "/", &bytes.Buffer{})
+	w := httptest.NewRecorder()
+	httpx.WriteProblem(w, r, p)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status '409' got '%d'", w.Code)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("expected content type 'application/problem+json' got '%s'", ct)
+	}
+
+	var doc map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc["type"] != "about:blank" || doc["title"] != "Conflict" || doc["status"] != 409.0 ||
+		doc["detail"] != "email already registered" || doc["email"] != "ab@c.com" {
+		t.Fatalf("unexpected problem document '%v'", doc)
+	}
+}
+
+func TestWriteProblemNegotiation(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected string
+	}{
+		{"text/html,application/xhtml+xml,*/*;q=0.8", "text/html; charset=utf-8"},
+		{"text/plain", "text/plain; charset=utf-8"},
+		{"application/json", "application/problem+json"},
+		{"text/*;q=0.5, application/*", "application/problem+json"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", &bytes.Buffer{})
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		httpx.WriteProblem(w, r, httpx.NewProblem(http.StatusNotFound, "missing"))
+
+		if ct := w.Header().Get("Content-Type"); ct != tt.expected {
+			t.Fatalf("accept '%s' expected content type '%s' got '%s'", tt.accept, tt.expected, ct)
+		}
+	}
+}
+
+func TestParseBodyProblem(t *testing.T) {
+	type user struct {
+		Name  string `form:"name,required"`
+		Email string `form:"email,required"`
+	}
+
+	h := httpx.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		var u user
+		return httpx.ParseBody(r, &u)
+	})
+
+	mux := httpx.NewServeMux()
+	mux.SetErrorHandler(httpx.ProblemErrorHandler)
+	mux.Handle("POST /users", h)
+
+	r := httptest.NewRequest("POST", "/users", strings.NewReader("name="))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status '422' got '%d'", w.Code)
+	}
+
+	var doc struct {
+		Status int                `json:"status"`
+		Errors []httpx.FieldError `json:"errors"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.Errors) != 1 || doc.Errors[0].Field != "email" {
+		t.Fatalf("expected error for field 'email' got '%v'", doc.Errors)
+	}
+}
@@ -27,7 +27,12 @@ func New(rdb *redis.Client) *RedisStore {
 // the data, a boolean indicating whether the token was found and
 // not expired, and an error.
 func (s *RedisStore) Get(token string) ([]byte, bool, error) {
-	data, err := s.rdb.Get(context.Background(), token).Bytes()
+	return s.GetCtx(context.Background(), token)
+}
+
+// GetCtx is like Get, using the context for the redis command.
+func (s *RedisStore) GetCtx(ctx context.Context, token string) ([]byte, bool, error) {
+	data, err := s.rdb.Get(ctx, token).Bytes()
 	if err != nil {
 		if err == redis.Nil {
 			return []byte{}, false, nil
@@ -43,11 +48,21 @@ func (s *RedisStore) Get(token string) ([]byte, bool, error) {
 // a record with the same token already exists, it is overwritten. The
 // expiresAt parameter specifies when the record should be considered expired.
 func (s *RedisStore) Set(token string, data []byte, expiresAt time.Time) error {
-	return s.rdb.Set(context.Background(), token, data, time.Until(expiresAt)).Err()
+	return s.SetCtx(context.Background(), token, data, expiresAt)
+}
+
+// SetCtx is like Set, using the context for the redis command.
+func (s *RedisStore) SetCtx(ctx context.Context, token string, data []byte, expiresAt time.Time) error {
+	return s.rdb.Set(ctx, token, data, time.Until(expiresAt)).Err()
 }
 
 // Delete removes the data associated with the given token. If the token
 // does not exist, this is a no-op.
 func (s *RedisStore) Delete(token string) error {
-	return s.rdb.Del(context.Background(), token).Err()
+	return s.DeleteCtx(context.Background(), token)
+}
+
+// DeleteCtx is like Delete, using the context for the redis command.
+func (s *RedisStore) DeleteCtx(ctx context.Context, token string) error {
+	return s.rdb.Del(ctx, token).Err()
 }
@@ -0,0 +1,387 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResponseCacheConfig is the configuration for the ResponseCache middleware.
+type ResponseCacheConfig struct {
+	// Store is where the responses are stored. It is required.
+	Store Store
+
+	// TTL is the time responses without an explicit freshness lifetime
+	// (Cache-Control max-age or s-maxage, or Expires) are stored. Zero
+	// means those responses aren't cached.
+	TTL time.Duration
+
+	// MaxBodySize is the maximum size of a response body that is stored
+	// (default: 1MB).
+	MaxBodySize int
+
+	// KeyPrefix is prepended to the keys of the store, so it can be
+	// shared with sessions (default: "cache:").
+	KeyPrefix string
+}
+
+var DefaultResponseCacheConfig = ResponseCacheConfig{
+	MaxBodySize: 1 << 20,
+	KeyPrefix:   "cache:",
+}
+
+// cachedResponse is a response as stored by ResponseCache. When the
+// response has a Vary header, the entry at the key of the URL only holds
+// the list of headers and the expiration of its longest lived variant, and
+// the responses are stored per variant.
+type cachedResponse struct {
+	Vary      []string
+	ExpiresAt time.Time
+	Status    int
+	Header    http.Header
+	Body      []byte
+	StoredAt  time.Time
+}
+
+// ResponseCache returns a middleware that caches complete responses to GET
+// requests in the store, with the default configuration. See
+// ResponseCacheWithConfig.
+func ResponseCache(store Store) Middleware {
+	cfg := DefaultResponseCacheConfig
+	cfg.Store = store
+	return ResponseCacheWithConfig(cfg)
+}
+
+// ResponseCacheWithConfig returns a middleware that caches complete
+// responses (status, headers and body) to GET requests in a Store, and
+// serves them to GET and HEAD requests without calling the handler.
+//
+// Only responses that can be stored by a shared cache are cached, following
+// RFC 9111: responses with "Cache-Control: no-store", "no-cache" or
+// "private", a Set-Cookie header or "Vary: *" aren't stored. The freshness
+// lifetime is taken from s-maxage, max-age or Expires, or TTL otherwise.
+// Requests with "Cache-Control: no-cache" or "max-age=0" skip the cached
+// response, and requests with "no-store" or an Authorization header skip
+// the cache entirely. Successful PUT, PATCH, POST and DELETE requests
+// invalidate the cached response of the URL.
+//
+// Responses are keyed by host and request URI as received by the server,
+// so groups sharing a store don't share responses. They are stored per
+// variant of the request headers listed in Vary, e.g. "Vary: Cookie" stores
+// a response per session.
+//
+// Conditional requests (If-None-Match and If-Modified-Since) are answered
+// with 304 Not Modified from the ETag and Last-Modified of the cached
+// response. Using the ETag middleware after the cache, the ETag is stored
+// with the response:
+//
+//	mux.Use(httpx.ResponseCache(memstore.New()), httpx.ETag())
+//
+//	mux.HandleFunc("GET /posts", func(w http.ResponseWriter, r *http.Request) {
+//		w.Header().Set("Cache-Control", "public, max-age=60")
+//		// ... render the posts
+//	})
+func ResponseCacheWithConfig(cfg ResponseCacheConfig) Middleware {
+	if cfg.Store == nil {
+		panic("httpx: ResponseCache requires a store")
+	}
+
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = DefaultResponseCacheConfig.MaxBodySize
+	}
+
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = DefaultResponseCacheConfig.KeyPrefix
+	}
+
+	store := NewStoreCtx(cfg.Store)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := cfg.KeyPrefix + r.Host + requestURI(r)
+
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				rw := NewResponseWriter(w)
+				next.ServeHTTP(rw, r)
+
+				if status := rw.Status(); status >= 200 && status < 400 && !isSafeMethod(r.Method) {
+					store.DeleteCtx(r.Context(), key)
+				}
+				return
+			}
+
+			reqCache := parseCacheControl(r.Header.Get("Cache-Control"))
+			if _, ok := reqCache["no-store"]; ok || r.Header.Get("Authorization") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			_, noCache := reqCache["no-cache"]
+			if maxAge, ok := reqCache["max-age"]; ok && maxAge == "0" {
+				noCache = true
+			}
+
+			if !noCache {
+				if cached, ok := loadResponse(store, key, r); ok {
+					serveCachedResponse(w, r, cached)
+					return
+				}
+			}
+
+			if r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &cacheWriter{ResponseWriter: NewResponseWriter(w), max: cfg.MaxBodySize}
+			next.ServeHTTP(cw, r)
+
+			if cw.skip {
+				return
+			}
+
+			// nothing written means an empty 200 OK response
+			if cw.status == 0 {
+				cw.status = http.StatusOK
+				cw.header = w.Header().Clone()
+			}
+
+			ttl := freshness(cw.header, cw.status, cfg.TTL)
+			if ttl <= 0 {
+				return
+			}
+
+			storeResponse(store, key, r, &cachedResponse{
+				Status:   cw.status,
+				Header:   cw.header,
+				Body:     cw.buf.Bytes(),
+				StoredAt: time.Now(),
+			}, ttl)
+		})
+	}
+}
+
+// cacheWriter copies the response into a buffer while sending it, so it
+// can be stored once the handler returns. Responses larger than max, flushed
+// or hijacked by the handler aren't stored.
+type cacheWriter struct {
+	*ResponseWriter
+	status int
+	header http.Header
+	buf    bytes.Buffer
+	max    int
+	skip   bool
+}
+
+var (
+	_ http.Flusher  = &cacheWriter{}
+	_ http.Hijacker = &cacheWriter{}
+	_ io.ReaderFrom = &cacheWriter{}
+)
+
+func (cw *cacheWriter) WriteHeader(status int) {
+	if cw.status == 0 && status >= 200 {
+		cw.status = status
+		cw.header = cw.ResponseWriter.Header().Clone()
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *cacheWriter) Write(data []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if !cw.skip {
+		if cw.buf.Len()+len(data) > cw.max {
+			cw.skip = true
+			cw.buf.Reset()
+		} else {
+			cw.buf.Write(data)
+		}
+	}
+	return cw.ResponseWriter.Write(data)
+}
+
+// ReadFrom copies r through Write, so the body is stored too.
+func (cw *cacheWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(writerOnly{cw}, r)
+}
+
+// Flush sends the response so far to the client. Flushed responses are
+// streamed, so they aren't stored.
+func (cw *cacheWriter) Flush() {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	cw.skip = true
+	cw.buf.Reset()
+	cw.ResponseWriter.Flush()
+}
+
+// Hijack lets the caller take over the connection, the response isn't
+// stored.
+func (cw *cacheWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	cw.skip = true
+	cw.buf.Reset()
+	return cw.ResponseWriter.Hijack()
+}
+
+// loadResponse returns the cached response for the request, following
+// the Vary entry if there is one.
+func loadResponse(store StoreCtx, key string, r *http.Request) (*cachedResponse, bool) {
+	cached, ok := getCachedResponse(r.Context(), store, key)
+	if !ok || len(cached.Vary) == 0 {
+		return cached, ok
+	}
+	return getCachedResponse(r.Context(), store, variantStoreKey(key, r, cached.Vary))
+}
+
+func getCachedResponse(ctx context.Context, store StoreCtx, key string) (*cachedResponse, bool) {
+	data, found, err := store.GetCtx(ctx, key)
+	if err != nil || !found {
+		return nil, false
+	}
+
+	var cached cachedResponse
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cached); err != nil {
+		return nil, false
+	}
+	return &cached, true
+}
+
+// storeResponse stores the response, and the Vary entry if the response
+// varies on request headers. The Vary entry is kept until the last of its
+// variants expires. Errors are ignored, as the response was already sent.
+func storeResponse(store StoreCtx, key string, r *http.Request, cached *cachedResponse, ttl time.Duration) {
+	expiresAt := cached.StoredAt.Add(ttl)
+
+	if vary := varyHeaders(cached.Header); len(vary) > 0 {
+		indexExpiresAt := expiresAt
+		if index, ok := getCachedResponse(r.Context(), store, key); ok && slices.Equal(index.Vary, vary) && index.ExpiresAt.After(indexExpiresAt) {
+			indexExpiresAt = index.ExpiresAt
+		}
+
+		index := &cachedResponse{Vary: vary, ExpiresAt: indexExpiresAt}
+		if err := setCachedResponse(r.Context(), store, key, index, indexExpiresAt); err != nil {
+			return
+		}
+		key = variantStoreKey(key, r, vary)
+	}
+
+	setCachedResponse(r.Context(), store, key, cached, expiresAt)
+}
+
+func setCachedResponse(ctx context.Context, store StoreCtx, key string, cached *cachedResponse, expiresAt time.Time) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cached); err != nil {
+		return err
+	}
+	return store.SetCtx(ctx, key, buf.Bytes(), expiresAt)
+}
+
+// variantStoreKey returns the key of the variant of the request, hashing
+// the header values so the key is safe for any store.
+func variantStoreKey(key string, r *http.Request, vary []string) string {
+	sum := sha256.Sum256([]byte(variantKey(r, vary)))
+	return key + "#" + hex.EncodeToString(sum[:16])
+}
+
+// serveCachedResponse writes the cached response, or 304 Not Modified if
+// the preconditions of the request are met.
+func serveCachedResponse(w http.ResponseWriter, r *http.Request, cached *cachedResponse) {
+	header := w.Header()
+	for name, values := range cached.Header {
+		header[name] = values
+	}
+
+	age := int(time.Since(cached.StoredAt).Seconds())
+	header.Set("Age", strconv.Itoa(age))
+
+	if cached.Status == http.StatusOK {
+		etag := cached.Header.Get("Etag")
+		if status := evalPreconditions(r, etag, lastModified(cached.Header)); status != 0 {
+			writePreconditionStatus(w, status, etag)
+			return
+		}
+	}
+
+	w.WriteHeader(cached.Status)
+	if r.Method != http.MethodHead {
+		w.Write(cached.Body)
+	}
+}
+
+// freshness returns the time the response can be stored by a shared cache,
+// or 0 if it can't be stored.
+func freshness(header http.Header, status int, ttl time.Duration) time.Duration {
+	if !isCacheableStatus(status) || header.Get("Set-Cookie") != "" {
+		return 0
+	}
+
+	if vary := varyHeaders(header); len(vary) == 1 && vary[0] == "*" {
+		return 0
+	}
+
+	cc := parseCacheControl(header.Get("Cache-Control"))
+	for _, directive := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := cc[directive]; ok {
+			return 0
+		}
+	}
+
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if value, ok := cc[directive]; ok {
+			seconds, err := strconv.Atoi(value)
+			if err != nil {
+				return 0
+			}
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	if value := header.Get("Expires"); value != "" {
+		expires, err := http.ParseTime(value)
+		if err != nil {
+			return 0
+		}
+		return time.Until(expires)
+	}
+
+	return ttl
+}
+
+// isCacheableStatus reports whether the status is cacheable by default, as
+// listed in RFC 9110 section 15.1.
+func isCacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusMultipleChoices, http.StatusMovedPermanently, http.StatusPermanentRedirect, http.StatusNotFound, http.StatusMethodNotAllowed,
+		http.StatusGone, http.StatusRequestURITooLong, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// parseCacheControl returns the directives of a Cache-Control header, with
+// lowercase names and unquoted values.
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, directive := range strings.Split(value, ",") {
+		name, val, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if name != "" {
+			directives[strings.ToLower(name)] = strings.Trim(val, `"`)
+		}
+	}
+	return directives
+}
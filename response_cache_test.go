@@ -0,0 +1,296 @@
+package httpx_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bluescreen10/httpx"
+)
+
+// newMapStore returns a mockstore backed by a map.
+func newMapStore() (*mockstore, map[string][]byte) {
+	data := map[string][]byte{}
+	return &mockstore{
+		get: func(key string) ([]byte, bool, error) {
+			value, ok := data[key]
+			return value, ok, nil
+		},
+		set: func(key string, value []byte, _ time.Time) error {
+			data[key] = value
+			return nil
+		},
+		delete: func(key string) error {
+			delete(data, key)
+			return nil
+		},
+	}, data
+}
+
+func TestResponseCache(t *testing.T) {
+	store, _ := newMapStore()
+	calls := 0
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "hello %d", calls)
+	})
+	handler := httpx.ResponseCache(store)(h)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/hello", nil)
+		handler.ServeHTTP(w, r)
+
+		if body := w.Body.String(); body != "hello 1" {
+			t.Fatalf("expected body 'hello 1' got '%s'", body)
+		}
+
+		if ct := w.Header().Get("Content-Type"); ct != "text/plain" {
+			t.Fatalf("expected content type 'text/plain' got '%s'", ct)
+		}
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected handler to be called once got '%d'", calls)
+	}
+
+	// HEAD is served from the cached GET response
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/hello", nil))
+
+	if calls != 1 || w.Body.Len() != 0 || w.Header().Get("Age") == "" {
+		t.Fatalf("expected cached HEAD response got '%d' calls and body '%s'", calls, w.Body)
+	}
+
+	// the client asks for a fresh response
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/hello", nil)
+	r.Header.Set("Cache-Control", "no-cache")
+	handler.ServeHTTP(w, r)
+
+	if body := w.Body.String(); body != "hello 2" {
+		t.Fatalf("expected body 'hello 2' got '%s'", body)
+	}
+}
+
+func TestResponseCacheNotStored(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		status int
+	}{
+		{"no cache control", nil, http.StatusOK},
+		{"no-store", map[string]string{"Cache-Control": "no-store"}, http.StatusOK},
+		{"private", map[string]string{"Cache-Control": "private, max-age=60"}, http.StatusOK},
+		{"set-cookie", map[string]string{"Cache-Control": "max-age=60", "Set-Cookie": "a=b"}, http.StatusOK},
+		{"vary all", map[string]string{"Cache-Control": "max-age=60", "Vary": "*"}, http.StatusOK},
+		{"status", map[string]string{"Cache-Control": "max-age=60"}, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		store, data := newMapStore()
+
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range tt.header {
+				w.Header().Set(name, value)
+			}
+			w.WriteHeader(tt.status)
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		httpx.ResponseCache(store)(h).ServeHTTP(w, r)
+
+		if len(data) != 0 {
+			t.Fatalf("%s: expected response not to be stored", tt.name)
+		}
+	}
+}
+
+func TestResponseCacheTTL(t *testing.T) {
+	store, data := newMapStore()
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+
+	cfg := httpx.DefaultResponseCacheConfig
+	cfg.Store = store
+	cfg.TTL = time.Minute
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	httpx.ResponseCacheWithConfig(cfg)(h).ServeHTTP(w, r)
+
+	if len(data) != 1 {
+		t.Fatalf("expected '1' stored response got '%d'", len(data))
+	}
+}
+
+func TestResponseCacheVary(t *testing.T) {
+	store, _ := newMapStore()
+	calls := 0
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		fmt.Fprintf(w, "hello %s", r.Header.Get("Accept-Language"))
+	})
+	handler := httpx.ResponseCache(store)(h)
+
+	for _, lang := range []string{"en", "es", "en", "es"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", lang)
+		handler.ServeHTTP(w, r)
+
+		if body := w.Body.String(); body != "hello "+lang {
+			t.Fatalf("expected body 'hello %s' got '%s'", lang, body)
+		}
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected handler to be called twice got '%d'", calls)
+	}
+}
+
+func TestResponseCacheETag(t *testing.T) {
+	store, _ := newMapStore()
+	calls := 0
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("hello world"))
+	})
+	handler := httpx.ResponseCache(store)(httpx.ETag()(h))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := w.Header().Get("ETag")
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("If-None-Match", etag)
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected status '%d' got '%d'", http.StatusNotModified, w.Code)
+	}
+
+	if got := w.Header().Get("ETag"); got != etag {
+		t.Fatalf("expected etag '%s' got '%s'", etag, got)
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected handler to be called once got '%d'", calls)
+	}
+}
+
+func TestResponseCacheInvalidation(t *testing.T) {
+	store, data := newMapStore()
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("hello"))
+	})
+	handler := httpx.ResponseCache(store)(h)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/posts/1", nil))
+	if len(data) != 1 {
+		t.Fatalf("expected '1' stored response got '%d'", len(data))
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/posts/1", nil))
+	if len(data) != 0 {
+		t.Fatalf("expected '0' stored responses got '%d'", len(data))
+	}
+}
+
+func TestResponseCacheGroups(t *testing.T) {
+	store, _ := newMapStore()
+	cache := httpx.ResponseCache(store)
+
+	mux := httpx.NewServeMux()
+	for _, name := range []string{"a", "b"} {
+		group := mux.Group("/"+name, cache)
+		group.HandleFunc("GET /x", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Write([]byte(name))
+		})
+	}
+
+	for _, name := range []string{"a", "b", "a", "b"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+name+"/x", nil))
+
+		if body := w.Body.String(); body != name {
+			t.Fatalf("expected body '%s' got '%s'", name, body)
+		}
+	}
+}
+
+func TestResponseCacheVaryExpiration(t *testing.T) {
+	store, _ := newMapStore()
+	expires := map[string]time.Time{}
+	set := store.set
+	store.set = func(key string, value []byte, exp time.Time) error {
+		expires[key] = exp
+		return set(key, value, exp)
+	}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age="+r.Header.Get("X-Max-Age"))
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte("hello"))
+	})
+	handler := httpx.ResponseCache(store)(h)
+
+	for _, maxAge := range []string{"60", "1"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", maxAge)
+		r.Header.Set("X-Max-Age", maxAge)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	// the short lived variant doesn't shorten the Vary entry
+	key := "cache:example.com/"
+	if exp := expires[key]; exp.Before(time.Now().Add(30 * time.Second)) {
+		t.Fatalf("expected vary entry to expire with the longest variant got '%s'", exp)
+	}
+}
+
+func TestResponseCacheFlush(t *testing.T) {
+	store, data := newMapStore()
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("hello"))
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("expected writer to implement http.Flusher")
+		}
+		flusher.Flush()
+	})
+	handler := httpx.ResponseCache(store)(h)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !w.Flushed || w.Body.String() != "hello" {
+		t.Fatalf("expected flushed body 'hello' got '%s'", w.Body)
+	}
+
+	// streamed responses aren't stored
+	if len(data) != 0 {
+		t.Fatalf("expected '0' stored responses got '%d'", len(data))
+	}
+}
@@ -1,39 +1,133 @@
 package httpx
 
 import (
+	"bufio"
 	"io"
+	"net"
 	"net/http"
 )
 
-type responseWriter struct {
-	header      http.Header
-	status      int
-	writer      io.Writer
-	writeHeader func(int)
+// ResponseWriter wraps an http.ResponseWriter to track the status code and
+// the number of bytes written, while still giving access to the optional
+// interfaces of the underlying writer. Flush, Hijack, ReadFrom and Push are
+// forwarded (returning http.ErrNotSupported when the underlying writer
+// doesn't support them), and Unwrap allows http.NewResponseController to
+// reach the underlying writer.
+//
+// Usage:
+//
+//	func middleware(next http.Handler) http.Handler {
+//		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//			rw := httpx.NewResponseWriter(w)
+//			next.ServeHTTP(rw, r)
+//			log.Println(rw.Status(), rw.Size())
+//		})
+//	}
+type ResponseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
 }
 
-var _ http.ResponseWriter = &responseWriter{}
+var (
+	_ http.ResponseWriter = &ResponseWriter{}
+	_ http.Flusher        = &ResponseWriter{}
+	_ http.Hijacker       = &ResponseWriter{}
+	_ http.Pusher         = &ResponseWriter{}
+	_ io.ReaderFrom       = &ResponseWriter{}
+)
+
+// NewResponseWriter returns a ResponseWriter that wraps w.
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+// Status returns the status code written, or 0 if nothing has been written
+// yet. Informational (1xx) status codes are not recorded.
+func (rw *ResponseWriter) Status() int {
+	return rw.status
+}
+
+// Size returns the number of bytes written to the body.
+func (rw *ResponseWriter) Size() int64 {
+	return rw.size
+}
+
+// Written reports whether the response header has been written.
+func (rw *ResponseWriter) Written() bool {
+	return rw.status != 0
+}
 
-func newResponseWriter(buf io.Writer, header http.Header, writeHeader func(int)) *responseWriter {
-	return &responseWriter{
-		header:      header,
-		writer:      buf,
-		writeHeader: writeHeader,
+// Unwrap returns the underlying http.ResponseWriter.
+func (rw *ResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+func (rw *ResponseWriter) WriteHeader(status int) {
+	if rw.status == 0 && (status >= 200 || status == http.StatusSwitchingProtocols) {
+		rw.status = status
 	}
+	rw.ResponseWriter.WriteHeader(status)
 }
 
-func (rw *responseWriter) Write(data []byte) (int, error) {
-	return rw.writer.Write(data)
+func (rw *ResponseWriter) Write(data []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	n, err := rw.ResponseWriter.Write(data)
+	rw.size += int64(n)
+	return n, err
 }
 
-func (rw *responseWriter) WriteHeader(status int) {
-	rw.status = status
-	if rw.writeHeader != nil {
-		rw.writeHeader(status)
+// ReadFrom copies r to the underlying writer, using its io.ReaderFrom
+// implementation when available (e.g. sendfile for files).
+func (rw *ResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	var n int64
+	var err error
+	if rf, ok := rw.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(writerOnly{rw.ResponseWriter}, r)
 	}
+	rw.size += n
+	return n, err
+}
 
+// Flush sends any buffered data to the client, if the underlying writer
+// supports it.
+func (rw *ResponseWriter) Flush() {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Hijack lets the caller take over the connection. It returns
+// http.ErrNotSupported if the underlying writer doesn't support it.
+func (rw *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil && rw.status == 0 {
+		rw.status = http.StatusSwitchingProtocols
+	}
+	return conn, buf, err
+}
+
+// Push initiates an HTTP/2 server push. It returns http.ErrNotSupported if
+// the underlying writer doesn't support it.
+func (rw *ResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := rw.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
 }
 
-func (rw *responseWriter) Header() http.Header {
-	return rw.header
+// writerOnly hides the optional interfaces of a writer, so io.Copy doesn't
+// call ReadFrom recursively.
+type writerOnly struct {
+	io.Writer
 }
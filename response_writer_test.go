@@ -0,0 +1,62 @@
+package httpx_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bluescreen10/httpx"
+)
+
+func TestResponseWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	rw := httpx.NewResponseWriter(w)
+
+	if rw.Written() {
+		t.Fatal("expected response not to be written")
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	rw.Write([]byte("hello"))
+	rw.ReadFrom(strings.NewReader(" world"))
+
+	if rw.Status() != http.StatusCreated {
+		t.Fatalf("expected status '201' got '%d'", rw.Status())
+	}
+
+	if rw.Size() != 11 || w.Body.String() != "hello world" {
+		t.Fatalf("expected '11' bytes got '%d' '%s'", rw.Size(), w.Body.String())
+	}
+
+	if rw.Unwrap() != w {
+		t.Fatal("expected Unwrap to return the underlying writer")
+	}
+}
+
+func TestResponseWriterFlush(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := http.NewResponseController(w).Hijack(); !errors.Is(err, http.ErrNotSupported) {
+			t.Fatalf("expected ErrNotSupported got '%v'", err)
+		}
+	})
+
+	logger := httpx.LoggerWithConfig(httpx.LoggerConfig{Format: "${status}", Output: &bytes.Buffer{}})
+
+	r := httptest.NewRequest("GET", "/", &bytes.Buffer{})
+	w := httptest.NewRecorder()
+	logger(h).ServeHTTP(w, r)
+
+	if !w.Flushed {
+		t.Fatal("expected the response to be flushed")
+	}
+}
@@ -0,0 +1,401 @@
+package httpx
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RotatingFileConfig is the configuration for a RotatingFile.
+type RotatingFileConfig struct {
+	// Filename is the path of the log file. Rotated files are kept in
+	// the same directory, named after it with a timestamp, e.g.
+	// "access-20060102T150405.000.log". A sequence number is added when
+	// there are several rotations in the same millisecond, e.g.
+	// "access-20060102T150405.000-1.log".
+	Filename string
+
+	// MaxSize is the size in bytes after which the file is rotated. Zero
+	// disables size based rotation.
+	MaxSize int64
+
+	// Interval is the time after which the file is rotated. Zero disables
+	// time based rotation.
+	Interval time.Duration
+
+	// MaxBackups is the number of rotated files to keep. Zero keeps them
+	// all.
+	MaxBackups int
+
+	// Compress gzips the rotated files.
+	Compress bool
+}
+
+// RotatingFile is an io.Writer that writes to a file and rotates it based
+// on size and/or time. It can be used as LoggerConfig.Output and is safe for
+// concurrent use.
+//
+// Usage:
+//
+//	out, err := httpx.NewRotatingFile(httpx.RotatingFileConfig{
+//		Filename:   "/var/log/app/access.log",
+//		MaxSize:    100 << 20,
+//		MaxBackups: 7,
+//		Compress:   true,
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer out.Close()
+//
+//	// reopen the file when an external tool (e.g. logrotate) moves it
+//	stop := out.ReopenOnSignal()
+//	defer stop()
+//
+//	cfg := httpx.CombinedLoggerConfig
+//	cfg.Output = out
+//	mux.Use(httpx.LoggerWithConfig(cfg))
+type RotatingFile struct {
+	cfg RotatingFileConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+
+	// the rotated files are compressed and cleaned up one at a time by a
+	// background worker, tracked by wg.
+	queueMu sync.Mutex
+	queue   []string
+	working bool
+	wg      sync.WaitGroup
+}
+
+var _ io.WriteCloser = &RotatingFile{}
+
+// NewRotatingFile opens (or creates) the file for appending.
+func NewRotatingFile(cfg RotatingFileConfig) (*RotatingFile, error) {
+	if cfg.Filename == "" {
+		return nil, errors.New("rotating file: filename is required")
+	}
+
+	f := &RotatingFile{cfg: cfg}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write writes p to the file, rotating it first if it's due. If the file
+// couldn't be opened by a previous rotation or reopen, it is opened again.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it with a timestamp and opens a
+// new one.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// Reopen closes and reopens the file without renaming it. This is meant for
+// external rotation tools that move the file and signal the process.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return os.ErrClosed
+	}
+
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	return f.open()
+}
+
+// ReopenOnSignal reopens the file every time the process receives one of
+// the signals (default: SIGHUP). The returned function stops listening.
+func (f *RotatingFile) ReopenOnSignal(signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, signals...)
+
+	go func() {
+		for {
+			select {
+			case <-ch:
+				f.Reopen()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
+
+// Close closes the file and waits for rotated files to be compressed and
+// cleaned up. Any later Write, Rotate or Reopen returns os.ErrClosed.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	f.closed = true
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.wg.Wait()
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.cfg.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+func (f *RotatingFile) shouldRotate(n int64) bool {
+	if f.cfg.MaxSize > 0 && f.size > 0 && f.size+n > f.cfg.MaxSize {
+		return true
+	}
+	return f.cfg.Interval > 0 && time.Since(f.openedAt) >= f.cfg.Interval
+}
+
+func (f *RotatingFile) rotate() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+
+	backup := f.nextBackupName(time.Now())
+	if err := os.Rename(f.cfg.Filename, backup); err == nil {
+		f.enqueue(backup)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// when the open fails, the next Write tries again
+	return f.open()
+}
+
+// enqueue adds the rotated file to the queue of the background worker,
+// starting it if it isn't running.
+func (f *RotatingFile) enqueue(backup string) {
+	f.queueMu.Lock()
+	defer f.queueMu.Unlock()
+
+	f.queue = append(f.queue, backup)
+	if f.working {
+		return
+	}
+
+	f.working = true
+	f.wg.Add(1)
+	go f.work()
+}
+
+// work compresses the queued rotated files and removes the old ones, until
+// the queue is empty. Doing both in the same goroutine ensures files being
+// compressed are never counted or removed.
+func (f *RotatingFile) work() {
+	defer f.wg.Done()
+
+	for {
+		f.queueMu.Lock()
+		if len(f.queue) == 0 {
+			f.working = false
+			f.queueMu.Unlock()
+			return
+		}
+		backup := f.queue[0]
+		f.queue = f.queue[1:]
+		f.queueMu.Unlock()
+
+		if f.cfg.Compress {
+			compressFile(backup)
+		}
+		f.removeOldBackups()
+	}
+}
+
+// nextBackupName returns the name of the rotated file, the timestamp is
+// added between the name and the extension. If a backup with the same
+// timestamp exists, compressed or not, a sequence number is added.
+func (f *RotatingFile) nextBackupName(t time.Time) string {
+	ext := filepath.Ext(f.cfg.Filename)
+	base := strings.TrimSuffix(f.cfg.Filename, ext)
+	stamp := base + "-" + t.Format(backupTimeFormat)
+
+	name := stamp + ext
+	for seq := 1; fileExists(name) || fileExists(name+".gz"); seq++ {
+		name = stamp + "-" + strconv.Itoa(seq) + ext
+	}
+	return name
+}
+
+func fileExists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+const backupTimeFormat = "20060102T150405.000"
+
+// backup is a rotated file, ordered by timestamp and sequence number.
+type backup struct {
+	name string
+	time time.Time
+	seq  int
+}
+
+// parseBackup parses the timestamp and sequence number of a rotated file
+// name, without the base name and extension, e.g.
+// "20060102T150405.000-1".
+func parseBackup(name, stamp string) (backup, bool) {
+	seq := 0
+	if i := strings.LastIndexByte(stamp, '-'); i >= 0 {
+		n, err := strconv.Atoi(stamp[i+1:])
+		if err != nil || n <= 0 {
+			return backup{}, false
+		}
+		stamp, seq = stamp[:i], n
+	}
+
+	t, err := time.Parse(backupTimeFormat, stamp)
+	if err != nil {
+		return backup{}, false
+	}
+	return backup{name: name, time: t, seq: seq}, true
+}
+
+// removeOldBackups deletes the oldest rotated files exceeding MaxBackups.
+func (f *RotatingFile) removeOldBackups() {
+	if f.cfg.MaxBackups <= 0 {
+		return
+	}
+
+	ext := filepath.Ext(f.cfg.Filename)
+	base := strings.TrimSuffix(f.cfg.Filename, ext)
+	matches, err := filepath.Glob(base + "-*" + ext + "*")
+	if err != nil {
+		return
+	}
+
+	var backups []backup
+	for _, match := range matches {
+		name := strings.TrimSuffix(match, ".gz")
+		stamp, ok := strings.CutSuffix(strings.TrimPrefix(name, base+"-"), ext)
+		if !ok {
+			continue
+		}
+
+		if b, ok := parseBackup(match, stamp); ok {
+			backups = append(backups, b)
+		}
+	}
+
+	slices.SortFunc(backups, func(a, b backup) int {
+		if c := a.time.Compare(b.time); c != 0 {
+			return c
+		}
+		return a.seq - b.seq
+	})
+
+	for len(backups) > f.cfg.MaxBackups {
+		os.Remove(backups[0].name)
+		backups = backups[1:]
+	}
+}
+
+// compressFile gzips the file and removes the original.
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
@@ -0,0 +1,205 @@
+package httpx_test
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bluescreen10/httpx"
+)
+
+func TestRotatingFileSize(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "access.log")
+
+	f, err := httpx.NewRotatingFile(httpx.RotatingFileConfig{
+		Filename:   filename,
+		MaxSize:    10,
+		MaxBackups: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"line 1\n", "line 2\n", "line 3\n", "line 4\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil || string(data) != "line 4\n" {
+		t.Fatalf("expected current file 'line 4' got '%s'", data)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "access-*.log"))
+	if len(backups) != 2 {
+		t.Fatalf("expected '2' backups got '%d'", len(backups))
+	}
+
+	// the rotations may happen in the same millisecond, no data is lost
+	// and the oldest backup is the one removed
+	var lines []string
+	for _, backup := range backups {
+		data, _ := os.ReadFile(backup)
+		lines = append(lines, string(data))
+	}
+	slices.Sort(lines)
+
+	if got := strings.Join(lines, ""); got != "line 2\nline 3\n" {
+		t.Fatalf("expected backups 'line 2' and 'line 3' got '%s'", got)
+	}
+}
+
+func TestRotatingFileSameMillisecond(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "access.log")
+
+	f, err := httpx.NewRotatingFile(httpx.RotatingFileConfig{Filename: filename, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		f.Write([]byte("line\n"))
+		if err := f.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "access-*.log.gz"))
+	if len(backups) != 5 {
+		t.Fatalf("expected '5' compressed backups got '%d'", len(backups))
+	}
+}
+
+func TestRotatingFileCompress(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "access.log")
+
+	f, err := httpx.NewRotatingFile(httpx.RotatingFileConfig{Filename: filename, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Write([]byte("hello world\n"))
+	if err := f.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "access-*.log.gz"))
+	if len(backups) != 1 {
+		t.Fatalf("expected '1' compressed backup got '%d'", len(backups))
+	}
+
+	gz, err := os.Open(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gz.Close()
+
+	zr, err := gzip.NewReader(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := io.ReadAll(zr)
+	if string(data) != "hello world\n" {
+		t.Fatalf("expected 'hello world' got '%s'", data)
+	}
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "access.log")
+
+	f, err := httpx.NewRotatingFile(httpx.RotatingFileConfig{Filename: filename})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("before\n"))
+	os.Rename(filename, filename+".1")
+
+	if err := f.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("after\n"))
+
+	data, _ := os.ReadFile(filename)
+	if !strings.HasPrefix(string(data), "after") {
+		t.Fatalf("expected reopened file to contain 'after' got '%s'", data)
+	}
+}
+
+func TestRotatingFileClosed(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "access.log")
+
+	f, err := httpx.NewRotatingFile(httpx.RotatingFileConfig{Filename: filename})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Reopen(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("expected error '%v' got '%v'", os.ErrClosed, err)
+	}
+
+	if err := f.Rotate(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("expected error '%v' got '%v'", os.ErrClosed, err)
+	}
+
+	if n, err := f.Write([]byte("x\n")); n != 0 || !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("expected error '%v' got '%d' and '%v'", os.ErrClosed, n, err)
+	}
+}
+
+func TestRotatingFileOpenRetry(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	os.Mkdir(dir, 0o755)
+	filename := filepath.Join(dir, "access.log")
+
+	f, err := httpx.NewRotatingFile(httpx.RotatingFileConfig{Filename: filename})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// the file can't be opened while the directory is missing
+	os.RemoveAll(dir)
+	if err := f.Reopen(); err == nil {
+		t.Fatal("expected error reopening in a missing directory")
+	}
+
+	if _, err := f.Write([]byte("lost\n")); err == nil {
+		t.Fatal("expected error writing in a missing directory")
+	}
+
+	os.Mkdir(dir, 0o755)
+	if _, err := f.Write([]byte("after\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(filename)
+	if string(data) != "after\n" {
+		t.Fatalf("expected 'after' got '%s'", data)
+	}
+}
@@ -0,0 +1,153 @@
+package httpx
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// routesTemplate renders the route table as an HTML page.
+var routesTemplate = template.Must(template.New("routes").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Routes</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 4px 12px; border-bottom: 1px solid #ddd; }
+td { font-family: monospace; }
+</style>
+</head>
+<body>
+<h1>Routes</h1>
+<table>
+<tr><th>Method</th><th>Host</th><th>Path</th><th>Name</th><th>Middlewares</th></tr>
+{{- range . }}
+<tr><td>{{ or .Method "*" }}</td><td>{{ .Host }}</td><td>{{ .Path }}{{ if .Mount }} (group){{ end }}</td><td>{{ .Name }}</td><td>{{ range $i, $m := .Middlewares }}{{ if $i }}, {{ end }}{{ $m }}{{ end }}</td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
+
+// routeInfo is the representation of a Route used by RoutesHandler.
+type routeInfo struct {
+	Method      string   `json:"method"`
+	Host        string   `json:"host"`
+	Path        string   `json:"path"`
+	Pattern     string   `json:"pattern"`
+	Mount       bool     `json:"mount"`
+	Name        string   `json:"name"`
+	Middlewares []string `json:"middlewares"`
+}
+
+// Routes returns the routes registered through Handle, HandleFunc and Static
+// on this mux and all its groups, in the order they were registered. The
+// mount points of the groups are included too, marked with Mount, with the
+// middlewares given to Group.
+func (mux *ServeMux) Routes() []*Route {
+	mux.routes.mu.RLock()
+	defer mux.routes.mu.RUnlock()
+
+	var routes []*Route
+	for _, route := range mux.routes.routes {
+		for m := route.mux; m != nil; m = m.parent {
+			if m == mux {
+				routes = append(routes, route)
+				break
+			}
+		}
+	}
+	return routes
+}
+
+// RoutesHandler returns a handler that renders the route table of the mux.
+// It is meant to be mounted during development to inspect what has been
+// registered:
+//
+//	mux.Handle("GET /_routes", mux.RoutesHandler())
+//
+// The table is rendered as JSON when the request accepts "application/json"
+// or has a "format=json" query parameter, and as HTML otherwise.
+func (mux *ServeMux) RoutesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routes := mux.Routes()
+		infos := make([]routeInfo, len(routes))
+		for i, route := range routes {
+			infos[i] = routeInfo{
+				Method:      route.Method,
+				Host:        route.Host,
+				Path:        route.Path,
+				Pattern:     route.Pattern,
+				Mount:       route.Mount,
+				Name:        route.GetName(),
+				Middlewares: route.MiddlewareNames(),
+			}
+		}
+
+		if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(infos)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		routesTemplate.Execute(w, infos)
+	})
+}
+
+// MiddlewareNames returns the names of the functions in the effective
+// middleware chain of the route, in the order they run.
+func (rt *Route) MiddlewareNames() []string {
+	middlewares := rt.Middlewares()
+	names := make([]string, len(middlewares))
+	for i, middleware := range middlewares {
+		names[i] = middlewareName(middleware)
+	}
+	return names
+}
+
+// middlewareName returns a readable name for the middleware based on the
+// function that created it, e.g. "httpx.LoggerWithConfig" for the closure
+// returned by httpx.Logger().
+func middlewareName(middleware Middleware) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(middleware).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	name = strings.TrimSuffix(name, "-fm")
+	for {
+		i := strings.LastIndex(name, ".")
+		if i < 0 || !isClosureSuffix(name[i+1:]) {
+			break
+		}
+		name = name[:i]
+	}
+	return name
+}
+
+// isClosureSuffix reports whether s is a compiler generated suffix for
+// closures, like "func1" or "1".
+func isClosureSuffix(s string) bool {
+	s = strings.TrimPrefix(s, "func")
+	if s == "" {
+		return false
+	}
+
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package httpx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bluescreen10/httpx"
+)
+
+func TestRoutes(t *testing.T) {
+	mux := httpx.NewServeMux()
+	mux.Use(httpx.Logger())
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {}).Name("home")
+	api := mux.Group("/api", httpx.ETag())
+	api.HandleFunc("POST example.com/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.Static("/static/", ".")
+
+	routes := mux.Routes()
+	if len(routes) != 4 {
+		t.Fatalf("expected '4' routes got '%d'", len(routes))
+	}
+
+	mount := routes[1]
+	if !mount.Mount || mount.Path != "/api/" {
+		t.Fatalf("expected group mount '/api/' got '%s'", mount.Path)
+	}
+
+	expected := "httpx.LoggerWithConfig,httpx.ETagWithConfig"
+	if got := strings.Join(mount.MiddlewareNames(), ","); got != expected {
+		t.Fatalf("expected mount middlewares '%s' got '%s'", expected, got)
+	}
+
+	user := routes[2]
+	if user.Method != "POST" || user.Host != "example.com" || user.Path != "/api/users/{id}" {
+		t.Fatalf("unexpected route '%s %s%s'", user.Method, user.Host, user.Path)
+	}
+
+	if got := strings.Join(user.MiddlewareNames(), ","); got != expected {
+		t.Fatalf("expected middlewares '%s' got '%s'", expected, got)
+	}
+
+	if n := len(api.Routes()); n != 1 {
+		t.Fatalf("expected '1' group route got '%d'", n)
+	}
+}
+
+func TestRoutesHandler(t *testing.T) {
+	mux := httpx.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {}).Name("user")
+	mux.Handle("GET /_routes", mux.RoutesHandler())
+
+	r := httptest.NewRequest("GET", "/_routes", &bytes.Buffer{})
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	var routes []map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&routes); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(routes) != 2 || routes[0]["name"] != "user" || routes[0]["path"] != "/users/{id}" {
+		t.Fatalf("unexpected routes '%v'", routes)
+	}
+
+	r = httptest.NewRequest("GET", "/_routes", &bytes.Buffer{})
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Fatalf("expected content type 'text/html; charset=utf-8' got '%s'", ct)
+	}
+
+	if !strings.Contains(w.Body.String(), "/users/{id}") {
+		t.Fatal("expected route table to contain '/users/{id}'")
+	}
+}
@@ -4,6 +4,11 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
 	"time"
 )
 
@@ -11,11 +16,14 @@ import (
 // It tracks creation time, modification status, and whether the session has
 // been destroyed.
 type Session struct {
-	id          string
-	createdAt   time.Time
-	values      map[string]any
-	isDestroyed bool
-	isModified  bool
+	id           string
+	renewedFrom  string
+	createdAt    time.Time
+	lastActivity time.Time
+	values       map[string]any
+	isDestroyed  bool
+	isModified   bool
+	isStored     bool
 }
 
 // newSession creates a new Session with a unique ID, current timestamp,
@@ -36,6 +44,18 @@ func (s *Session) Destroy() {
 	s.isDestroyed = true
 }
 
+// Renew replaces the session token with a new one, keeping the values.
+// The values are moved to the new token in the store and the old one is
+// deleted when the session is saved. It should be called when the privilege
+// level changes, e.g. after login, to prevent session fixation attacks.
+func (s *Session) Renew() {
+	if s.renewedFrom == "" {
+		s.renewedFrom = s.id
+	}
+	s.id = genUUIDv7()
+	s.isModified = true
+}
+
 // Set adds or updates a value in the session. Marks the session as modified.
 func (s *Session) Set(key string, value interface{}) {
 	s.isModified = true
@@ -53,6 +73,12 @@ func (s *Session) GetCreatedAt() time.Time {
 	return s.createdAt
 }
 
+// GetLastActivity returns the time the session was last saved, or the zero
+// time for new sessions.
+func (s *Session) GetLastActivity() time.Time {
+	return s.lastActivity
+}
+
 // GetID returns the session's unique identifier.
 func (s *Session) GetID() string {
 	return s.id
@@ -65,47 +91,213 @@ func (s *Session) Get(key string) interface{} {
 }
 
 // GetInt retrieves an int value from the session. Returns 0 if not found or
-// type mismatch.
+// type mismatch. Numbers of other types are converted, see SessionGet.
 func (s *Session) GetInt(key string) int {
-	v, _ := s.values[key].(int)
+	v, _ := SessionGet[int](s, key)
 	return v
 }
 
 // GetUint retrieves a uint value from the session. Returns 0 if not found or
-// type mismatch.
+// type mismatch. Numbers of other types are converted, see SessionGet.
 func (s *Session) GetUint(key string) uint {
-	v, _ := s.values[key].(uint)
+	v, _ := SessionGet[uint](s, key)
 	return v
 }
 
 // GetBool retrieves a bool value from the session. Returns false if not found
 // or type mismatch.
 func (s *Session) GetBool(key string) bool {
-	v, _ := s.values[key].(bool)
+	v, _ := SessionGet[bool](s, key)
 	return v
 }
 
 // GetFloat32 retrieves a float32 value from the session. Returns 0 if not found
-// or type mismatch.
+// or type mismatch. Numbers of other types are converted, see SessionGet.
 func (s *Session) GetFloat32(key string) float32 {
-	v, _ := s.values[key].(float32)
+	v, _ := SessionGet[float32](s, key)
 	return v
 }
 
 // GetFloat64 retrieves a float64 value from the session. Returns 0 if not found
-// or type mismatch.
+// or type mismatch. Numbers of other types are converted, see SessionGet.
 func (s *Session) GetFloat64(key string) float64 {
-	v, _ := s.values[key].(float64)
+	v, _ := SessionGet[float64](s, key)
 	return v
 }
 
 // GetString retrieves a string value from the session. Returns "" if not found
 // or type mismatch.
 func (s *Session) GetString(key string) string {
-	v, _ := s.values[key].(string)
+	v, _ := SessionGet[string](s, key)
 	return v
 }
 
+// Has reports whether the session has a value for the key. Keys reserved
+// for internal use, like the pending flashes, are never reported.
+func (s *Session) Has(key string) bool {
+	_, ok := s.values[key]
+	return ok && !isReservedKey(key)
+}
+
+// Keys returns the keys of the session values, sorted, excluding the keys
+// reserved for internal use.
+func (s *Session) Keys() []string {
+	keys := slices.Sorted(maps.Keys(s.values))
+	return slices.DeleteFunc(keys, isReservedKey)
+}
+
+// isReservedKey reports whether the key is used internally by the package.
+func isReservedKey(key string) bool {
+	return key == flashesKey
+}
+
+// SessionGet retrieves the value of the key from the session as a T. It
+// returns false if the key doesn't exist or the value isn't a T.
+//
+// Numbers are converted between numeric types when T is numeric and the
+// conversion is lossless, since codecs may not keep the original type
+// (e.g. JSON decodes all numbers as float64 or json.Number). For example,
+// float64(3) is returned as int(3), but float64(3.5) or int(-1) as a uint
+// are a mismatch, as are float64(0.1) as a float32 and integers above 2^53
+// that a float64 can't represent exactly.
+//
+// Values of other types, like structs, must be registered with gob.Register
+// to be stored with GobCodec.
+//
+// Usage:
+//
+//	userID, ok := httpx.SessionGet[int](sess, "user_id")
+//	if !ok {
+//		http.Redirect(w, r, "/login", http.StatusSeeOther)
+//		return
+//	}
+func SessionGet[T any](s *Session, key string) (T, bool) {
+	var zero T
+
+	value, ok := s.values[key]
+	if !ok {
+		return zero, false
+	}
+
+	if v, ok := value.(T); ok {
+		return v, true
+	}
+
+	return convertNumber[T](value)
+}
+
+// SessionPop retrieves the value of the key from the session as a T, like
+// SessionGet, and removes it from the session if found.
+func SessionPop[T any](s *Session, key string) (T, bool) {
+	v, ok := SessionGet[T](s, key)
+	if ok {
+		s.Delete(key)
+	}
+	return v, ok
+}
+
+// convertNumber converts the numeric value to T without losing precision.
+// It returns false if either isn't numeric or the value can't be
+// represented exactly as a T.
+func convertNumber[T any](value any) (T, bool) {
+	var zero T
+
+	if n, ok := value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			value = i
+		} else if f, err := n.Float64(); err == nil {
+			value = f
+		} else {
+			return zero, false
+		}
+	}
+
+	src := reflect.ValueOf(value)
+	dst := reflect.New(reflect.TypeFor[T]()).Elem()
+
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch src.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i = src.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if src.Uint() > math.MaxInt64 {
+				return zero, false
+			}
+			i = int64(src.Uint())
+		case reflect.Float32, reflect.Float64:
+			f := src.Float()
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return zero, false
+			}
+			i = int64(f)
+		default:
+			return zero, false
+		}
+
+		if dst.OverflowInt(i) {
+			return zero, false
+		}
+		dst.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		switch src.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if src.Int() < 0 {
+				return zero, false
+			}
+			u = uint64(src.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			u = src.Uint()
+		case reflect.Float32, reflect.Float64:
+			f := src.Float()
+			if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+				return zero, false
+			}
+			u = uint64(f)
+		default:
+			return zero, false
+		}
+
+		if dst.OverflowUint(u) {
+			return zero, false
+		}
+		dst.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch src.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(src.Int())
+			if f >= math.MaxInt64 || int64(f) != src.Int() {
+				return zero, false
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			f = float64(src.Uint())
+			if f >= math.MaxUint64 || uint64(f) != src.Uint() {
+				return zero, false
+			}
+		case reflect.Float32, reflect.Float64:
+			f = src.Float()
+		default:
+			return zero, false
+		}
+
+		// the value must round trip, e.g. 0.1 isn't exact as a float32
+		dst.SetFloat(f)
+		if dst.Float() != f && !math.IsNaN(f) {
+			return zero, false
+		}
+
+	default:
+		return zero, false
+	}
+
+	return dst.Interface().(T), true
+}
+
 // Delete removes a value from the session and marks it as modified.
 func (s *Session) Delete(key string) {
 	s.isModified = true
@@ -42,6 +42,7 @@ import (
 
 type sessionResponseWriter struct {
 	http.ResponseWriter
+	ctx       context.Context
 	mngr      *SessionManager
 	sess      *Session
 	isWritten bool
@@ -50,7 +51,7 @@ type sessionResponseWriter struct {
 func (w *sessionResponseWriter) Write(b []byte) (int, error) {
 	if !w.isWritten {
 		w.isWritten = true
-		w.mngr.Save(w.ResponseWriter, w.sess)
+		w.mngr.SaveCtx(w.ctx, w.ResponseWriter, w.sess)
 	}
 	return w.ResponseWriter.Write(b)
 }
@@ -58,19 +59,26 @@ func (w *sessionResponseWriter) Write(b []byte) (int, error) {
 func (w *sessionResponseWriter) WriteHeader(statusCode int) {
 	if !w.isWritten {
 		w.isWritten = true
-		w.mngr.Save(w.ResponseWriter, w.sess)
+		w.mngr.SaveCtx(w.ctx, w.ResponseWriter, w.sess)
 	}
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Unwrap returns the underlying http.ResponseWriter, so it can be reached
+// by http.NewResponseController.
+func (w *sessionResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // SessionManager manages HTTP sessions using a Store backend and session options.
 type SessionManager struct {
-	store       Store
-	lifetime    time.Duration
-	idleTimeout time.Duration
-	codec       Codec
-	cookie      CookieConfig
-	key         *struct{}
+	store          StoreCtx
+	lifetime       time.Duration
+	idleTimeout    time.Duration
+	codec          Codec
+	cookie         CookieConfig
+	renewCreatedAt bool
+	key            *struct{}
 }
 
 type CookieConfig struct {
@@ -96,8 +104,16 @@ func (m *SessionManager) SetCookieConfig(cfg CookieConfig) {
 	m.cookie = cfg
 }
 
+// SetRenewCreatedAt sets whether renewing the session token also resets its
+// creation time, extending the session lifetime. By default the creation
+// time is kept, so renewing doesn't extend the lifetime.
+func (m *SessionManager) SetRenewCreatedAt(renew bool) {
+	m.renewCreatedAt = renew
+}
+
 // Handler method is a middleware that provides load-and-save session functionality.
 // It ensures that the session is loaded from the store and saved after the request.
+// The request context is passed to the store, see StoreCtx.
 func (m *SessionManager) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Cookie")
@@ -107,18 +123,18 @@ func (m *SessionManager) Handler(next http.Handler) http.Handler {
 		if err == nil {
 			token = cookie.Value
 		}
-		sess, err := m.Load(token)
+		sess, err := m.LoadCtx(r.Context(), token)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
 		sr := r.WithContext(context.WithValue(r.Context(), m.key, sess))
-		sw := &sessionResponseWriter{w, m, sess, false}
+		sw := &sessionResponseWriter{w, r.Context(), m, sess, false}
 		next.ServeHTTP(sw, sr)
 
 		if !sw.isWritten {
-			m.Save(w, sess)
+			m.SaveCtx(r.Context(), w, sess)
 		}
 	})
 }
@@ -133,15 +149,29 @@ func (m *SessionManager) Get(r *http.Request) *Session {
 	return sess
 }
 
-// Load retrieves a session from the store by token. If the token is empty
-// or the session is not found, a new session is created.
+// RenewToken replaces the token of the current session with a new one,
+// keeping the values, to prevent session fixation attacks. It should be
+// called when the privilege level changes (e.g. after login or logout),
+// before writing the response. See Session.Renew.
+func (m *SessionManager) RenewToken(r *http.Request) {
+	m.Get(r).Renew()
+}
+
+// Load retrieves a session from the store by token. If the token is empty,
+// the session is not found or it has expired, a new session is created.
+// Sessions expire after the lifetime since they were created, or after the
+// idle timeout since their last activity.
 func (m *SessionManager) Load(token string) (*Session, error) {
+	return m.LoadCtx(context.Background(), token)
+}
 
+// LoadCtx is like Load, passing the context to the store.
+func (m *SessionManager) LoadCtx(ctx context.Context, token string) (*Session, error) {
 	if token == "" {
 		return newSession(), nil
 	}
 
-	data, found, err := m.store.Get(token)
+	data, found, err := m.store.GetCtx(ctx, token)
 	if err != nil {
 		return nil, err
 	}
@@ -150,19 +180,48 @@ func (m *SessionManager) Load(token string) (*Session, error) {
 		return newSession(), nil
 	}
 
-	createdAt, values, err := m.codec.Decode(data)
+	createdAt, lastActivity, values, err := m.decode(data)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Session{id: token, createdAt: createdAt, values: values}, nil
+	now := time.Now()
+	if now.After(createdAt.Add(m.lifetime)) {
+		return newSession(), nil
+	}
+
+	if m.idleTimeout > 0 && !lastActivity.IsZero() && now.After(lastActivity.Add(m.idleTimeout)) {
+		return newSession(), nil
+	}
+
+	return &Session{
+		id:           token,
+		createdAt:    createdAt,
+		lastActivity: lastActivity,
+		values:       values,
+		isStored:     true,
+	}, nil
 }
 
 // Save persists the session to the store and updates the HTTP cookie.
 // Destroyed sessions are deleted from the store and expired cookies are set.
+// With an idle timeout, stored sessions are saved on every request to record
+// the activity and extend their expiration.
 func (m *SessionManager) Save(w http.ResponseWriter, sess *Session) error {
+	return m.SaveCtx(context.Background(), w, sess)
+}
+
+// SaveCtx is like Save, passing the context to the store.
+func (m *SessionManager) SaveCtx(ctx context.Context, w http.ResponseWriter, sess *Session) error {
 	if sess.isDestroyed {
-		err := m.store.Delete(sess.id)
+		if sess.renewedFrom != "" {
+			if err := m.store.DeleteCtx(ctx, sess.renewedFrom); err != nil {
+				return err
+			}
+			sess.renewedFrom = ""
+		}
+
+		err := m.store.DeleteCtx(ctx, sess.id)
 		if err != nil {
 			return err
 		}
@@ -170,30 +229,65 @@ func (m *SessionManager) Save(w http.ResponseWriter, sess *Session) error {
 		return nil
 	}
 
+	now := time.Now()
+	if sess.renewedFrom != "" && m.renewCreatedAt {
+		sess.createdAt = now
+	}
+
 	expiresAt := sess.createdAt.Add(m.lifetime)
+	if m.idleTimeout > 0 {
+		idleExpires := now.Add(m.idleTimeout)
+		if idleExpires.Before(expiresAt) {
+			expiresAt = idleExpires
+		}
+	}
 
-	if sess.isModified {
-		sess.isModified = false
-		data, err := m.codec.Encode(sess.createdAt, sess.values)
+	if sess.isModified || (m.idleTimeout > 0 && sess.isStored) {
+		data, err := m.encode(sess.createdAt, now, sess.values)
 		if err != nil {
 			return err
 		}
-		err = m.store.Set(sess.id, data, expiresAt)
+		err = m.store.SetCtx(ctx, sess.id, data, expiresAt)
 		if err != nil {
 			return err
 		}
+		sess.isModified = false
+		sess.isStored = true
+		sess.lastActivity = now
 	}
 
-	if m.idleTimeout > 0 {
-		idleExpires := time.Now().Add(m.idleTimeout)
-		if idleExpires.Before(expiresAt) {
-			expiresAt = idleExpires
+	// the values are stored with the new token, the old one can go
+	if sess.renewedFrom != "" {
+		if err := m.store.DeleteCtx(ctx, sess.renewedFrom); err != nil {
+			return err
 		}
+		sess.renewedFrom = ""
 	}
+
 	m.writeCookie(w, sess.id, expiresAt)
 	return nil
 }
 
+// decode decodes the session data, with the last activity time if the codec
+// stores it, or the zero time otherwise.
+func (m *SessionManager) decode(data []byte) (createdAt, lastActivity time.Time, values map[string]any, err error) {
+	if codec, ok := m.codec.(CodecWithActivity); ok {
+		return codec.DecodeWithActivity(data)
+	}
+
+	createdAt, values, err = m.codec.Decode(data)
+	return createdAt, time.Time{}, values, err
+}
+
+// encode encodes the session data, with the last activity time if the codec
+// stores it.
+func (m *SessionManager) encode(createdAt, lastActivity time.Time, values map[string]any) ([]byte, error) {
+	if codec, ok := m.codec.(CodecWithActivity); ok {
+		return codec.EncodeWithActivity(createdAt, lastActivity, values)
+	}
+	return m.codec.Encode(createdAt, values)
+}
+
 func (m *SessionManager) writeCookie(w http.ResponseWriter, token string, expiresAt time.Time) {
 	cookie := &http.Cookie{
 		Value:       token,
@@ -225,7 +319,7 @@ func NewSessionManager(store Store) *SessionManager {
 	mngr := &SessionManager{
 		lifetime: 24 * time.Hour,
 		codec:    GobCodec{},
-		store:    store,
+		store:    NewStoreCtx(store),
 		cookie: CookieConfig{
 			Name:      "session_id",
 			Path:      "/",
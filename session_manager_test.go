@@ -2,9 +2,12 @@ package httpx_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
 	"time"
 
@@ -368,3 +371,318 @@ func TestSessionValues(t *testing.T) {
 	h1 := sm.Handler(h)
 	h1.ServeHTTP(w, r)
 }
+
+func TestSessionTimeValue(t *testing.T) {
+	store, _ := newMapStore()
+	sm := httpx.NewSessionManager(store)
+	loginAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	h1 := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess := sm.Get(r)
+		sess.Set("login_at", loginAt)
+		sess.Set("roles", []string{"admin"})
+	})
+
+	w := httptest.NewRecorder()
+	sm.Handler(h1).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected '1' cookie got '%d'", len(cookies))
+	}
+
+	h2 := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess := sm.Get(r)
+		if v, ok := httpx.SessionGet[time.Time](sess, "login_at"); !ok || !v.Equal(loginAt) {
+			t.Fatalf("expected '%s' got '%s'", loginAt, v)
+		}
+
+		if v, ok := httpx.SessionGet[[]string](sess, "roles"); !ok || !slices.Equal(v, []string{"admin"}) {
+			t.Fatalf("expected '[admin]' got '%v'", v)
+		}
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookies[0])
+	sm.Handler(h2).ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestRenewToken(t *testing.T) {
+	store, data := newMapStore()
+	sm := httpx.NewSessionManager(store)
+
+	h1 := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sm.Get(r).Set("user_id", 123)
+	})
+
+	w1 := httptest.NewRecorder()
+	sm.Handler(h1).ServeHTTP(w1, httptest.NewRequest("GET", "/", nil))
+	oldCookie := w1.Result().Cookies()[0]
+
+	var createdAt time.Time
+	h2 := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		createdAt = sm.Get(r).GetCreatedAt()
+		sm.RenewToken(r)
+	})
+
+	r2 := httptest.NewRequest("POST", "/login", nil)
+	r2.AddCookie(oldCookie)
+	w2 := httptest.NewRecorder()
+	sm.Handler(h2).ServeHTTP(w2, r2)
+	newCookie := w2.Result().Cookies()[0]
+
+	if newCookie.Value == oldCookie.Value {
+		t.Fatalf("expected a new token got '%s'", newCookie.Value)
+	}
+
+	if _, ok := data[oldCookie.Value]; ok {
+		t.Fatal("expected old token to be deleted")
+	}
+
+	h3 := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess := sm.Get(r)
+		if id := sess.GetInt("user_id"); id != 123 {
+			t.Fatalf("expected value '123' got '%d'", id)
+		}
+
+		if !sess.GetCreatedAt().Equal(createdAt) {
+			t.Fatalf("expected created at '%s' got '%s'", createdAt, sess.GetCreatedAt())
+		}
+	})
+
+	r3 := httptest.NewRequest("GET", "/", nil)
+	r3.AddCookie(newCookie)
+	sm.Handler(h3).ServeHTTP(httptest.NewRecorder(), r3)
+}
+
+func TestSessionIdleTimeoutEnforced(t *testing.T) {
+	store, data := newMapStore()
+	sm := httpx.NewSessionManager(store)
+	sm.SetIdleTimeout(10 * time.Minute)
+
+	now := time.Now()
+	data["active"], _ = httpx.GobCodec{}.EncodeWithActivity(now.Add(-time.Hour), now.Add(-time.Minute), map[string]any{"user_id": 1})
+	data["idle"], _ = httpx.GobCodec{}.EncodeWithActivity(now.Add(-time.Hour), now.Add(-11*time.Minute), map[string]any{"user_id": 2})
+	data["expired"], _ = httpx.GobCodec{}.EncodeWithActivity(now.Add(-25*time.Hour), now.Add(-time.Minute), map[string]any{"user_id": 3})
+
+	tests := []struct {
+		token  string
+		userID int
+	}{
+		{"active", 1},
+		{"idle", 0},
+		{"expired", 0},
+	}
+
+	for _, tt := range tests {
+		sess, err := sm.Load(tt.token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if id := sess.GetInt("user_id"); id != tt.userID {
+			t.Fatalf("%s: expected value '%d' got '%d'", tt.token, tt.userID, id)
+		}
+	}
+}
+
+func TestSessionIdleTimeoutRefresh(t *testing.T) {
+	store, data := newMapStore()
+	sm := httpx.NewSessionManager(store)
+	sm.SetIdleTimeout(10 * time.Minute)
+
+	now := time.Now()
+	data["abc123"], _ = httpx.GobCodec{}.EncodeWithActivity(now.Add(-time.Hour), now.Add(-5*time.Minute), map[string]any{})
+
+	var expiresAt time.Time
+	set := store.set
+	store.set = func(token string, value []byte, exp time.Time) error {
+		expiresAt = exp
+		return set(token, value, exp)
+	}
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", "session_id=abc123")
+	sm.Handler(h).ServeHTTP(httptest.NewRecorder(), r)
+
+	if expiresAt.Before(now.Add(9 * time.Minute)) {
+		t.Fatalf("expected store expiration to be refreshed got '%s'", expiresAt)
+	}
+
+	_, lastActivity, _, err := httpx.GobCodec{}.DecodeWithActivity(data["abc123"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lastActivity.Before(now) {
+		t.Fatalf("expected last activity to be updated got '%s'", lastActivity)
+	}
+}
+
+func TestSessionIdleTimeoutWithoutActivity(t *testing.T) {
+	store, data := newMapStore()
+	sm := httpx.NewSessionManager(store)
+	sm.SetIdleTimeout(10 * time.Minute)
+
+	// sessions encoded without the last activity are left to the store
+	data["abc123"], _ = httpx.GobCodec{}.Encode(time.Now().Add(-time.Hour), map[string]any{"user_id": 1})
+
+	sess, err := sm.Load("abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id := sess.GetInt("user_id"); id != 1 {
+		t.Fatalf("expected value '1' got '%d'", id)
+	}
+}
+
+type ctxstore struct {
+	mockstore
+	ctx context.Context
+}
+
+func (s *ctxstore) GetCtx(ctx context.Context, token string) ([]byte, bool, error) {
+	s.ctx = ctx
+	return s.get(token)
+}
+
+func (s *ctxstore) SetCtx(ctx context.Context, token string, data []byte, expiresAt time.Time) error {
+	s.ctx = ctx
+	return s.set(token, data, expiresAt)
+}
+
+func (s *ctxstore) DeleteCtx(ctx context.Context, token string) error {
+	s.ctx = ctx
+	return s.delete(token)
+}
+
+var _ httpx.StoreCtx = &ctxstore{}
+
+func TestSessionStoreCtx(t *testing.T) {
+	store, _ := newMapStore()
+	cs := &ctxstore{mockstore: *store}
+	sm := httpx.NewSessionManager(cs)
+
+	type key struct{}
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sm.Get(r).Set("hello", "world")
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), key{}, "value"))
+	r.Header.Set("Cookie", "session_id=abc123")
+	sm.Handler(h).ServeHTTP(httptest.NewRecorder(), r)
+
+	if cs.ctx == nil || cs.ctx.Value(key{}) != "value" {
+		t.Fatal("expected request context to be passed to the store")
+	}
+}
+
+func TestSessionStoreCtxAdapter(t *testing.T) {
+	store := &mockstore{}
+	store.get = func(string) ([]byte, bool, error) {
+		t.Fatal("unexpected call to store get")
+		return nil, false, nil
+	}
+	sm := httpx.NewSessionManager(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := sm.LoadCtx(ctx, "abc123"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected '%v' got '%v'", context.Canceled, err)
+	}
+}
+
+func TestSessionGet(t *testing.T) {
+	store, _ := newMapStore()
+	sm := httpx.NewSessionManager(store)
+
+	type userID int
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess := sm.Get(r)
+		sess.Set("int", 1)
+		sess.Set("json", float64(2))
+		sess.Set("number", json.Number("3"))
+		sess.Set("fraction", 3.5)
+		sess.Set("negative", -1)
+		sess.Set("time", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		sess.Set("big", int64(1<<53+1))
+		sess.Set("precise", 0.1)
+		sess.Flash(httpx.FlashInfo, "pending")
+
+		if v, ok := httpx.SessionGet[int](sess, "int"); !ok || v != 1 {
+			t.Fatalf("expected '1' got '%d'", v)
+		}
+
+		if v, ok := httpx.SessionGet[int](sess, "json"); !ok || v != 2 {
+			t.Fatalf("expected '2' got '%d'", v)
+		}
+
+		if v, ok := httpx.SessionGet[userID](sess, "number"); !ok || v != 3 {
+			t.Fatalf("expected '3' got '%d'", v)
+		}
+
+		if v := sess.GetInt("json"); v != 2 {
+			t.Fatalf("expected '2' got '%d'", v)
+		}
+
+		if v, ok := httpx.SessionGet[int](sess, "fraction"); ok {
+			t.Fatalf("expected mismatch got '%d'", v)
+		}
+
+		if v, ok := httpx.SessionGet[uint](sess, "negative"); ok {
+			t.Fatalf("expected mismatch got '%d'", v)
+		}
+
+		if v, ok := httpx.SessionGet[int8](sess, "int"); !ok || v != 1 {
+			t.Fatalf("expected '1' got '%d'", v)
+		}
+
+		if v, ok := httpx.SessionGet[float64](sess, "big"); ok {
+			t.Fatalf("expected mismatch got '%f'", v)
+		}
+
+		if v, ok := httpx.SessionGet[float64](sess, "int"); !ok || v != 1 {
+			t.Fatalf("expected '1' got '%f'", v)
+		}
+
+		if v, ok := httpx.SessionGet[float32](sess, "precise"); ok {
+			t.Fatalf("expected mismatch got '%f'", v)
+		}
+
+		if v, ok := httpx.SessionGet[float32](sess, "fraction"); !ok || v != 3.5 {
+			t.Fatalf("expected '3.5' got '%f'", v)
+		}
+
+		if v, ok := httpx.SessionGet[string](sess, "int"); ok {
+			t.Fatalf("expected mismatch got '%s'", v)
+		}
+
+		if v, ok := httpx.SessionGet[time.Time](sess, "time"); !ok || v.Year() != 2025 {
+			t.Fatalf("expected '2025' got '%d'", v.Year())
+		}
+
+		if !sess.Has("time") || sess.Has("missing") || sess.Has("_flashes") {
+			t.Fatal("expected 'time' key only")
+		}
+
+		if v, ok := httpx.SessionPop[float64](sess, "fraction"); !ok || v != 3.5 {
+			t.Fatalf("expected '3.5' got '%f'", v)
+		}
+
+		keys := sess.Keys()
+		expected := []string{"big", "int", "json", "negative", "number", "precise", "time"}
+		if !slices.Equal(keys, expected) {
+			t.Fatalf("expected keys '%v' got '%v'", expected, keys)
+		}
+	})
+
+	sm.Handler(h).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
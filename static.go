@@ -0,0 +1,390 @@
+package httpx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// StaticConfig is the configuration for StaticFS.
+type StaticConfig struct {
+	// Index is the file served for directories (default: "index.html").
+	Index string
+
+	// SPA serves the root Index file for paths that don't exist and
+	// don't have a file extension, so client side routes of single page
+	// applications can be loaded directly.
+	SPA bool
+
+	// Browse enables directory listings for directories without an
+	// Index file.
+	Browse bool
+
+	// HideDotFiles hides files and directories whose name starts with
+	// a dot, as if they didn't exist.
+	HideDotFiles bool
+
+	// Fingerprint computes a content hash of every file when mounted and
+	// serves the file under "name.<hash>.ext" too, with a far-future
+	// immutable Cache-Control. ServeMux.Asset and the "asset" template
+	// function map the logical paths to the fingerprinted ones.
+	Fingerprint bool
+}
+
+// precompressed are the encodings of the precompressed siblings of a file
+// that are looked up, in order of preference, and their file extensions.
+var precompressed = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"zstd", ".zst"},
+	{"gzip", ".gz"},
+}
+
+var DefaultStaticConfig = StaticConfig{
+	Index: "index.html",
+}
+
+// StaticFS mounts a file server for the given fs.FS (e.g. an embed.FS) with
+// the given prefix, configuration and optional middlewares.
+//
+// When a file has precompressed siblings (app.js.br, app.js.zst or
+// app.js.gz) and the client accepts the encoding, the sibling is served with
+// the corresponding Content-Encoding.
+//
+// Usage:
+//
+//	//go:embed dist
+//	var dist embed.FS
+//
+//	assets, _ := fs.Sub(dist, "dist")
+//	mux.StaticFS("/", assets, httpx.StaticConfig{SPA: true})
+func (mux *ServeMux) StaticFS(prefix string, fsys fs.FS, cfg StaticConfig, middlewares ...Middleware) *Route {
+	static := newStaticHandler(fsys, cfg)
+	handler := chain(static, middlewares)
+
+	route := mux.Handle(prefix, http.StripPrefix(prefix, handler))
+	route.middlewares = middlewares
+
+	base := strings.TrimSuffix(route.Path, "/") + "/"
+	mux.routes.mu.Lock()
+	for name, fingerprinted := range static.assets {
+		mux.routes.assets[base+name] = base + fingerprinted
+	}
+	mux.routes.mu.Unlock()
+	return route
+}
+
+// Asset returns the fingerprinted path of a file served by StaticFS with
+// Fingerprint enabled, e.g. "/static/app.js" becomes "/static/app.3f9a1c08.js".
+// Unknown paths are returned unchanged.
+func (mux *ServeMux) Asset(name string) string {
+	mux.routes.mu.RLock()
+	defer mux.routes.mu.RUnlock()
+
+	if fingerprinted, ok := mux.routes.assets[name]; ok {
+		return fingerprinted
+	}
+	return name
+}
+
+// staticHandler serves the files of an fs.FS.
+type staticHandler struct {
+	fsys fs.FS
+	cfg  StaticConfig
+
+	// assets maps file names to their fingerprinted names, and
+	// fingerprints the other way around.
+	assets       map[string]string
+	fingerprints map[string]string
+}
+
+func newStaticHandler(fsys fs.FS, cfg StaticConfig) *staticHandler {
+	if cfg.Index == "" {
+		cfg.Index = DefaultStaticConfig.Index
+	}
+
+	if cfg.HideDotFiles {
+		fsys = dotFileHidingFS{fsys}
+	}
+
+	h := &staticHandler{fsys: fsys, cfg: cfg}
+	if cfg.Fingerprint {
+		h.computeFingerprints()
+	}
+	return h
+}
+
+// computeFingerprints hashes every file of the fs, except the
+// precompressed siblings that are served along the original file.
+func (h *staticHandler) computeFingerprints() {
+	h.assets = make(map[string]string)
+	h.fingerprints = make(map[string]string)
+
+	fs.WalkDir(h.fsys, ".", func(name string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() || isPrecompressed(name) {
+			return nil
+		}
+
+		data, err := fs.ReadFile(h.fsys, name)
+		if err != nil {
+			return nil
+		}
+
+		sum := sha256.Sum256(data)
+		ext := path.Ext(name)
+		fingerprinted := strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+
+		h.assets[name] = fingerprinted
+		h.fingerprints[fingerprinted] = name
+		return nil
+	})
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	urlPath := r.URL.Path
+	if !strings.HasPrefix(urlPath, "/") {
+		urlPath = "/" + urlPath
+	}
+
+	name := strings.TrimPrefix(path.Clean(urlPath), "/")
+	if name == "" {
+		name = "."
+	}
+
+	if original, ok := h.fingerprints[name]; ok {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		h.serveFile(w, r, original)
+		return
+	}
+
+	info, err := fs.Stat(h.fsys, name)
+	if err != nil {
+		if h.cfg.SPA && path.Ext(name) == "" {
+			h.serveFile(w, r, h.cfg.Index)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	if !info.IsDir() {
+		h.serveFile(w, r, name)
+		return
+	}
+
+	// directories are always served with a trailing slash, so relative
+	// links inside the index work
+	if !strings.HasSuffix(urlPath, "/") {
+		redirectDir(w, r)
+		return
+	}
+
+	index := path.Join(name, h.cfg.Index)
+	if _, err := fs.Stat(h.fsys, index); err == nil {
+		h.serveFile(w, r, index)
+		return
+	}
+
+	if h.cfg.Browse {
+		h.serveDir(w, r, name)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// serveFile writes the file with the given name, handling range and
+// conditional requests. If the client accepts it, a precompressed sibling
+// of the file is written instead.
+func (h *staticHandler) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	f, encoding, variants := h.openPrecompressed(r, name)
+	if variants {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+
+	if f == nil {
+		var err error
+		f, err = h.fsys.Open(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	defer f.Close()
+
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+		// the type must come from the original name, not the sibling's
+		if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+			w.Header().Set("Content-Type", ctype)
+		}
+	}
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	http.ServeContent(w, r, path.Base(name), info.ModTime(), content)
+}
+
+// openPrecompressed opens the preferred precompressed sibling of the file
+// accepted by the client. It returns a nil file if there is none, and
+// whether the file has any precompressed sibling at all.
+func (h *staticHandler) openPrecompressed(r *http.Request, name string) (f fs.File, encoding string, variants bool) {
+	acceptEncoding := r.Header.Get("Accept-Encoding")
+
+	for _, p := range precompressed {
+		if _, err := fs.Stat(h.fsys, name+p.ext); err != nil {
+			continue
+		}
+
+		variants = true
+		if f != nil || !acceptsEncoding(acceptEncoding, p.encoding) {
+			continue
+		}
+
+		if sibling, err := h.fsys.Open(name + p.ext); err == nil {
+			f, encoding = sibling, p.encoding
+		}
+	}
+	return f, encoding, variants
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header allows the
+// encoding, either explicitly or through "*", with a non-zero q-value.
+func acceptsEncoding(acceptEncoding, encoding string) bool {
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+
+		s := -1
+		switch coding {
+		case encoding:
+			s = 1
+		case "*":
+			s = 0
+		}
+
+		if s <= specificity {
+			continue
+		}
+
+		specificity, q = s, 1
+		key, value, _ := strings.Cut(strings.TrimSpace(params), "=")
+		if strings.EqualFold(key, "q") {
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				q = v
+			}
+		}
+	}
+	return q > 0
+}
+
+// isPrecompressed reports whether the file is a precompressed sibling.
+func isPrecompressed(name string) bool {
+	for _, p := range precompressed {
+		if strings.HasSuffix(name, p.ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveDir writes a listing of the directory.
+func (h *staticHandler) serveDir(w http.ResponseWriter, r *http.Request, name string) {
+	entries, err := fs.ReadDir(h.fsys, name)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	dirListTemplate.Execute(w, entries)
+}
+
+var dirListTemplate = template.Must(template.New("dir").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+</head>
+<body>
+<pre>
+{{- range . }}
+{{- $name := .Name }}{{ if .IsDir }}{{ $name = printf "%s/" .Name }}{{ end }}
+<a href="{{ $name }}">{{ $name }}</a>
+{{- end }}
+</pre>
+</body>
+</html>
+`))
+
+// redirectDir redirects to the same path with a trailing slash, relative to
+// the current path so it works behind http.StripPrefix.
+func redirectDir(w http.ResponseWriter, r *http.Request) {
+	target := path.Base(r.URL.Path) + "/"
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, http.StatusMovedPermanently)
+}
+
+// dotFileHidingFS is an fs.FS that hides files and directories whose name
+// starts with a dot.
+type dotFileHidingFS struct {
+	fs.FS
+}
+
+func (fsys dotFileHidingFS) Open(name string) (fs.File, error) {
+	if hasDotFile(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return fsys.FS.Open(name)
+}
+
+func (fsys dotFileHidingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if hasDotFile(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries, err := fs.ReadDir(fsys.FS, name)
+	var visible []fs.DirEntry
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ".") {
+			visible = append(visible, entry)
+		}
+	}
+	return visible, err
+}
+
+// hasDotFile reports whether any element of the path starts with a dot.
+func hasDotFile(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if part != "." && strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package httpx_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/bluescreen10/httpx"
+)
+
+var staticFS = fstest.MapFS{
+	"index.html":        {Data: []byte("<html>app</html>")},
+	"app.js":            {Data: []byte("console.log('app')")},
+	".env":              {Data: []byte("SECRET=1")},
+	"docs/readme.txt":   {Data: []byte("readme")},
+	"docs/.hidden.txt":  {Data: []byte("hidden")},
+	"public/index.html": {Data: []byte("public")},
+}
+
+func TestStaticFS(t *testing.T) {
+	mux := httpx.NewServeMux()
+	mux.StaticFS("/assets/", staticFS, httpx.DefaultStaticConfig)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/assets/app.js", http.StatusOK, "console.log('app')"},
+		{"/assets/", http.StatusOK, "<html>app</html>"},
+		{"/assets/public/", http.StatusOK, "public"},
+		{"/assets/missing", http.StatusNotFound, ""},
+		{"/assets/docs/", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.path, &bytes.Buffer{})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Fatalf("%s: expected status '%d' got '%d'", tt.path, tt.status, w.Code)
+		}
+
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Fatalf("%s: expected body '%s' got '%s'", tt.path, tt.body, w.Body.String())
+		}
+	}
+}
+
+func TestStaticFSSPA(t *testing.T) {
+	mux := httpx.NewServeMux()
+	mux.StaticFS("/", staticFS, httpx.StaticConfig{SPA: true})
+
+	r := httptest.NewRequest("GET", "/users/42", &bytes.Buffer{})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK || w.Body.String() != "<html>app</html>" {
+		t.Fatalf("expected index for client route got '%d' '%s'", w.Code, w.Body.String())
+	}
+
+	r = httptest.NewRequest("GET", "/missing.js", &bytes.Buffer{})
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status '404' for missing asset got '%d'", w.Code)
+	}
+}
+
+func TestStaticFSDotFiles(t *testing.T) {
+	mux := httpx.NewServeMux()
+	mux.StaticFS("/", staticFS, httpx.StaticConfig{Browse: true, HideDotFiles: true})
+
+	r := httptest.NewRequest("GET", "/.env", &bytes.Buffer{})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status '404' got '%d'", w.Code)
+	}
+
+	r = httptest.NewRequest("GET", "/docs/", &bytes.Buffer{})
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	body := w.Body.String()
+	if !strings.Contains(body, "readme.txt") || strings.Contains(body, ".hidden.txt") {
+		t.Fatalf("unexpected directory listing '%s'", body)
+	}
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.js":    {Data: []byte("raw")},
+		"app.js.gz": {Data: []byte("gzip")},
+		"app.js.br": {Data: []byte("brotli")},
+	}
+
+	mux := httpx.NewServeMux()
+	mux.StaticFS("/", fsys, httpx.DefaultStaticConfig)
+
+	tests := []struct {
+		acceptEncoding string
+		encoding       string
+		body           string
+	}{
+		{"gzip, deflate, br", "br", "brotli"},
+		{"gzip", "gzip", "gzip"},
+		{"br;q=0, gzip", "gzip", "gzip"},
+		{"", "", "raw"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/app.js", &bytes.Buffer{})
+		r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if enc := w.Header().Get("Content-Encoding"); enc != tt.encoding {
+			t.Fatalf("expected encoding '%s' got '%s'", tt.encoding, enc)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Fatalf("expected body '%s' got '%s'", tt.body, body)
+		}
+
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+			t.Fatalf("expected content type 'text/javascript' got '%s'", ct)
+		}
+
+		if vary := w.Header().Get("Vary"); vary != "Accept-Encoding" {
+			t.Fatalf("expected vary 'Accept-Encoding' got '%s'", vary)
+		}
+	}
+}
+
+func TestStaticFingerprint(t *testing.T) {
+	mux := httpx.NewServeMux()
+	mux.StaticFS("/static/", staticFS, httpx.StaticConfig{Fingerprint: true})
+
+	asset := mux.Asset("/static/app.js")
+	if asset == "/static/app.js" || !strings.HasPrefix(asset, "/static/app.") || !strings.HasSuffix(asset, ".js") {
+		t.Fatalf("unexpected fingerprinted asset '%s'", asset)
+	}
+
+	r := httptest.NewRequest("GET", asset, &bytes.Buffer{})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK || w.Body.String() != "console.log('app')" {
+		t.Fatalf("expected asset content got '%d' '%s'", w.Code, w.Body.String())
+	}
+
+	if cc := w.Header().Get("Cache-Control"); cc != "public, max-age=31536000, immutable" {
+		t.Fatalf("expected immutable cache control got '%s'", cc)
+	}
+
+	if got := mux.Asset("/static/unknown.js"); got != "/static/unknown.js" {
+		t.Fatalf("expected unknown asset unchanged got '%s'", got)
+	}
+}
@@ -1,11 +1,15 @@
 package httpx
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // Store defines the interface for session storage backends.
 // A Store is responsible for persisting and retrieving session data
 // by a unique session token. Implementations may store sessions in
 // memory, databases, caches, or any other durable storage system.
+// It is also used by ResponseCache to store responses.
 type Store interface {
 	// Get retrieves the session data associated with the given token.
 	// It returns the raw session data, a boolean indicating whether
@@ -22,3 +26,53 @@ type Store interface {
 	// return an error if the session does not exist.
 	Delete(token string) error
 }
+
+// StoreCtx is implemented by stores that take a context in every call, so
+// the deadline, cancellation and values of the request (e.g. for tracing)
+// are propagated to the backend. SessionManager and ResponseCache use it
+// when the store implements it.
+type StoreCtx interface {
+	// GetCtx is like Store.Get with a context.
+	GetCtx(ctx context.Context, token string) (data []byte, found bool, err error)
+
+	// SetCtx is like Store.Set with a context.
+	SetCtx(ctx context.Context, token string, data []byte, expiresAt time.Time) error
+
+	// DeleteCtx is like Store.Delete with a context.
+	DeleteCtx(ctx context.Context, token string) error
+}
+
+// NewStoreCtx returns the store as a StoreCtx. If the store doesn't
+// implement StoreCtx, it is adapted by checking the context before every
+// call, as the call itself can't be cancelled.
+func NewStoreCtx(store Store) StoreCtx {
+	if s, ok := store.(StoreCtx); ok {
+		return s
+	}
+	return storeCtxAdapter{store}
+}
+
+type storeCtxAdapter struct {
+	store Store
+}
+
+func (s storeCtxAdapter) GetCtx(ctx context.Context, token string) ([]byte, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	return s.store.Get(token)
+}
+
+func (s storeCtxAdapter) SetCtx(ctx context.Context, token string, data []byte, expiresAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.store.Set(token, data, expiresAt)
+}
+
+func (s storeCtxAdapter) DeleteCtx(ctx context.Context, token string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.store.Delete(token)
+}